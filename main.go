@@ -1,82 +1,42 @@
 package main
 
 import (
-	"bufio"  // Para leer línea por línea desde la entrada estándar
-	"fmt"    // Para formatear y mostrar salida
-	"os"     // Para interactuar con el sistema operativo
-	"os/user" // Para obtener información del usuario actual
+	"context" // Para ejecutar scripts con RunFile
+	"fmt"     // Para formatear y mostrar salida
+	"os"      // Para interactuar con el sistema operativo
+
+	"shell-reto-go/pkg/goshell" // Intérprete de la shell
 )
 
-// main es la función principal que implementa el bucle REPL (Bucle de lectura-evaluación-impresión)
-// de la shell. Ejecuta indefinidamente hasta que el usuario ejecute el comando "exit"
+// main es la función principal de la shell: crea el intérprete, ejecuta el
+// bucle REPL (o el script indicado como argumento) y, cuando termina, ejecuta
+// la limpieza y sale con el estado indicado por exit
+//
+// Uso:
+//   - goshell: shell interactiva
+//   - goshell script.sh: ejecuta el script y termina con su estado
 func main() {
-	// Mostrar mensaje de bienvenida al iniciar la shell
-	mostrarBienvenida()
-	
-	// Crear un lector para capturar la entrada del usuario desde stdin
-	// bufio.NewReader es más eficiente que fmt.Scan para leer líneas completas
-	lector := bufio.NewReader(os.Stdin)
-
-	// Bucle infinito que implementa el REPL de la shell
-	for {
-		// PASO 1: Obtener información para mostrar en el prompt
-		
-		// Obtener el directorio de trabajo actual para mostrarlo en el prompt
-		wd, err := os.Getwd()
-		if err != nil {
-			// Si hay error obteniendo el directorio, mostrar el error y usar cadena vacía
-			fmt.Fprintln(os.Stderr, "Error al obtener el directorio actual:", err)
-			wd = ""
-		}
+	// Crear la shell conectada a los flujos estándar y al entorno del proceso
+	sh := goshell.NuevaShell()
 
-		// Obtener información del usuario actual para personalizar el prompt
-		currentUser, err := user.Current()
-		if err != nil {
-			// Si hay error obteniendo el usuario, mostrar el error pero continuar
-			fmt.Fprintln(os.Stderr, "Error al obtener el usuario actual:", err)
-		}
-
-		// PASO 2: Mostrar el prompt y leer la entrada del usuario
-		
-		// Mostrar prompt colorizado en formato "usuario:directorio goshell> "
-		// Usando códigos ANSI para colores
-		mostrarPrompt(currentUser.Username, wd)
+	// Preparar la respuesta a las señales dirigidas a la shell (ej: SIGHUP)
+	sh.IniciarManejoSenales()
 
-		// Leer una línea completa de entrada hasta encontrar '\n' (Enter)
-		// ReadString incluye el carácter delimitador en el resultado
-		entrada, err := lector.ReadString('\n')
+	// Con un argumento, ejecutar el script sin mostrar la bienvenida
+	if len(os.Args) > 1 {
+		estado, err := sh.RunFile(context.Background(), os.Args[1])
 		if err != nil {
-			// Si hay error leyendo (ej: EOF), mostrar error y continuar el bucle
-			fmt.Fprintln(os.Stderr, "Error al leer la entrada:", err)
-			continue
+			fmt.Fprintln(os.Stderr, "goshell:", err)
 		}
+		os.Exit(sh.Finalizar(estado))
+	}
 
-		// PASO 3: Analizar y procesar la entrada del usuario
-		
-		// Parsear la línea de entrada para extraer:
-		// - comando: el programa o comando a ejecutar
-		// - args: lista de argumentos para el comando
-		// - segundoPlano: boolean que indica si debe ejecutarse en background (&)
-		comando, args, segundoPlano := AnalizarEntrada(entrada)
-
-		// Si no hay comando (línea vacía o solo espacios), continuar al siguiente ciclo
-		// Esto evita errores al intentar ejecutar comandos vacíos
-		// También se ignoran líneas que solo contienen espacios o tabulaciones
-		if comando == "" {
-			continue
-		}
+	// Mostrar mensaje de bienvenida al iniciar la shell
+	mostrarBienvenida()
 
-		// PASO 4: Ejecutar el comando
-		
-		// Intentar ejecutar el comando (interno o externo) con sus argumentos
-		// Si hay error en la ejecución, mostrarlo pero no terminar la shell
-		if err := EjecutarComando(comando, args, segundoPlano); err != nil {
-			fmt.Fprintln(os.Stderr, "Error al ejecutar el comando:", err)
-		}
-		
-		// El bucle continúa para procesar el siguiente comando
-		// Solo se rompe cuando se ejecuta el comando interno "exit"
-	}
+	// Ejecutar el REPL y salir con el estado que devuelva, después de limpiar
+	estado := sh.EjecutarREPL()
+	os.Exit(sh.Finalizar(estado))
 }
 
 // mostrarBienvenida muestra un mensaje de bienvenida colorizado al iniciar la shell
@@ -138,6 +98,7 @@ func mostrarBienvenida() {
 	fmt.Printf("%s%sComandos Internos Disponibles:%s\n", ColorAmarillo, ColorNegrita, ColorReset)
 	fmt.Printf("  %s• cd [directorio]%s  - Cambiar directorio (sin args = ir a home)\n", ColorCian, ColorReset)
 	fmt.Printf("  %s• exit%s             - Salir de la shell\n", ColorCian, ColorReset)
+	fmt.Printf("  %s• help [comando]%s   - Ver todos los comandos internos y su ayuda\n", ColorCian, ColorReset)
 
 	fmt.Printf("\n%s%sComandos Externos:%s\n", ColorAmarillo, ColorNegrita, ColorReset)
 	fmt.Printf("  %s• ls, cat, echo, grep, etc.%s - Cualquier programa en tu PATH\n", ColorCian, ColorReset)
@@ -155,28 +116,3 @@ func mostrarBienvenida() {
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 }
 
-// mostrarPrompt muestra el prompt colorizado de la shell
-// Formato: usuario:directorio goshell>
-func mostrarPrompt(usuario, directorio string) {
-	// Definir códigos de color ANSI
-	const (
-		ColorReset    = "\033[0m"
-		ColorVerde    = "\033[32m"
-		ColorAzul     = "\033[34m"
-		ColorMagenta  = "\033[35m"
-		ColorCian     = "\033[36m"
-		ColorNegrita  = "\033[1m"
-	)
-
-	// Acortar el directorio si es muy largo (mostrar solo los últimos 40 caracteres)
-	dirMostrar := directorio
-	if len(directorio) > 40 {
-		dirMostrar = "..." + directorio[len(directorio)-37:]
-	}
-
-	// Mostrar prompt colorizado: usuario en verde, directorio en azul, "goshell>" en magenta
-	fmt.Printf("%s%s%s%s:%s%s%s%s %s%sgoshell>%s ",
-		ColorVerde, ColorNegrita, usuario, ColorReset,
-		ColorAzul, ColorNegrita, dirMostrar, ColorReset,
-		ColorMagenta, ColorNegrita, ColorReset)
-}
@@ -0,0 +1,333 @@
+// Módulo analizador: Se encarga del parsing y análisis de la entrada del usuario
+// Implementa la lógica para convertir una línea de texto en componentes ejecutables
+package goshell
+
+import (
+	"errors"  // Para crear errores de expansión
+	"fmt"     // Para formatear los errores de sintaxis
+	"os"      // Para obtener el PID de la shell ($$)
+	"strconv" // Para convertir $?, $$ y $! en texto
+	"strings" // Para manipulación de cadenas de texto
+)
+
+// AnalizarEntrada es la función principal de parsing que procesa la línea de entrada del usuario.
+// 
+// Funcionalidad:
+// 1. Limpia espacios en blanco al inicio y final
+// 2. Detecta si el comando debe ejecutarse en segundo plano (sufijo &)
+// 3. Separa el comando principal de sus argumentos
+// 
+// Parámetros:
+//   - entrada: string que contiene la línea completa ingresada por el usuario
+// 
+// Retorna:
+//   - string: el comando principal a ejecutar (ej: "ls", "cat", "cd")
+//   - []string: slice con los argumentos del comando (ej: ["-l", "/tmp"])
+//   - bool: true si debe ejecutarse en segundo plano (encontró &), false en caso contrario
+//
+// Ejemplos:
+//   - "ls -l /tmp" → ("ls", ["-l", "/tmp"], false)
+//   - "sleep 5 &" → ("sleep", ["5"], true)
+//   - "   " → ("", nil, false)
+func AnalizarEntrada(entrada string) (string, []string, bool) {
+	// PASO 1: Limpiar la entrada removiendo espacios al inicio y final
+	// strings.TrimSpace elimina espacios, tabs, saltos de línea, etc.
+	entrada = strings.TrimSpace(entrada)
+	
+	// Si la entrada está vacía después de limpiar, no hay nada que procesar
+	if entrada == "" {
+		return "", nil, false
+	}
+
+	// PASO 2: Detectar ejecución en segundo plano
+	// Inicializar la bandera de segundo plano como false
+	segundoPlano := false
+	
+	// Verificar si la línea termina con el carácter "&"
+	// strings.HasSuffix verifica si la cadena termina con el sufijo dado
+	if strings.HasSuffix(entrada, "&") {
+		// Marcar que debe ejecutarse en segundo plano
+		segundoPlano = true
+		
+		// Remover el "&" de la entrada y limpiar espacios nuevamente
+		// strings.TrimSuffix remueve el sufijo especificado del final
+		entrada = strings.TrimSpace(strings.TrimSuffix(entrada, "&"))
+	}
+
+	// PASO 3: Separar comando y argumentos
+	// strings.Fields divide la cadena en palabras separadas por espacios
+	// Automáticamente maneja múltiples espacios consecutivos
+	partes := strings.Fields(entrada)
+
+	// Si la línea solo contenía "&" no queda ningún comando
+	if len(partes) == 0 {
+		return "", nil, segundoPlano
+	}
+
+	// El primer elemento es siempre el comando principal
+	comando := partes[0]
+	
+	// Los elementos restantes son los argumentos (puede ser slice vacío)
+	// partes[1:] crea un slice con todos los elementos desde el índice 1 en adelante
+	args := partes[1:]
+
+	// Retornar los tres componentes analizados
+	return comando, args, segundoPlano
+}
+
+// Redireccion describe una redirección de E/S de un comando (ej: "> salida.txt").
+type Redireccion struct {
+	Descriptor int    // Descriptor redirigido: 0 (stdin), 1 (stdout) o 2 (stderr)
+	Operador   string // "<", ">", ">>", ">|" (ignora noclobber) o ">&" (duplicar descriptor)
+	Destino    string // Ruta del archivo, o número de descriptor para ">&"
+}
+
+// Comando es un comando simple dentro de una tubería: nombre, argumentos y
+// redirecciones.
+type Comando struct {
+	Nombre        string
+	Args          []string
+	Redirecciones []Redireccion
+}
+
+// Tuberia es una secuencia de comandos conectados con "|", donde la salida de
+// cada comando es la entrada del siguiente.
+type Tuberia struct {
+	Comandos     []Comando
+	SegundoPlano bool   // true si la línea terminaba con &
+	Texto        string // Línea original, para mostrar en la tabla de trabajos
+}
+
+// operadoresRedireccion asocia cada prefijo de redirección con el descriptor
+// que redirige y el operador normalizado. Se recorren en orden, por lo que los
+// operadores más largos van primero (">>" antes que ">").
+var operadoresRedireccion = []struct {
+	prefijo    string
+	descriptor int
+	operador   string
+}{
+	{"2>&1", 2, ">&"},
+	{"1>&2", 1, ">&"},
+	{">&2", 1, ">&"},
+	{"2>>", 2, ">>"},
+	{"2>|", 2, ">|"},
+	{"2>", 2, ">"},
+	{">>", 1, ">>"},
+	{">|", 1, ">|"},
+	{">", 1, ">"},
+	{"<", 0, "<"},
+}
+
+// AnalizarTuberia procesa una línea completa que puede contener tuberías (|)
+// y redirecciones (<, >, >>, >|, 2>, 2>>, 2>&1). Se apoya en AnalizarEntrada
+// para separar las palabras y detectar el sufijo &.
+//
+// Los operadores pueden escribirse separados del archivo ("> out.txt") o
+// pegados a él (">out.txt"). El operador "|" debe ir separado por espacios.
+//
+// Parámetros:
+//   - entrada: línea completa ingresada por el usuario
+//
+// Retorna:
+//   - *Tuberia: la tubería analizada (nil si la línea está vacía)
+//   - error: error de sintaxis (ej: "|" sin comando, redirección sin archivo)
+//
+// Ejemplos:
+//   - "ls -l | grep go > lista.txt" → [ls -l] | [grep go, stdout→lista.txt]
+//   - "sort < datos.txt &" → [sort, stdin←datos.txt], segundo plano
+func AnalizarTuberia(entrada string) (*Tuberia, error) {
+	// PASO 1: Separar palabras y detectar el sufijo & con el analizador básico
+	comando, args, segundoPlano := AnalizarEntrada(entrada)
+	if comando == "" {
+		if segundoPlano {
+			return nil, errorSintaxis("&")
+		}
+		return nil, nil
+	}
+	palabras := append([]string{comando}, args...)
+
+	tub := &Tuberia{SegundoPlano: segundoPlano, Texto: strings.Join(palabras, " ")}
+	actual := Comando{}
+
+	// PASO 2: Recorrer las palabras separando comandos y redirecciones
+	for i := 0; i < len(palabras); i++ {
+		palabra := palabras[i]
+
+		// Fin de un comando de la tubería
+		if palabra == "|" {
+			if actual.Nombre == "" {
+				return nil, errorSintaxis("|")
+			}
+			tub.Comandos = append(tub.Comandos, actual)
+			actual = Comando{}
+			continue
+		}
+
+		// Redirección: el destino va pegado al operador o en la palabra siguiente
+		if red, ok := analizarRedireccion(palabra); ok {
+			if red.Destino == "" && red.Operador != ">&" {
+				if i+1 >= len(palabras) || palabras[i+1] == "|" {
+					return nil, errorSintaxis(palabra)
+				}
+				i++
+				red.Destino = palabras[i]
+			}
+			actual.Redirecciones = append(actual.Redirecciones, red)
+			continue
+		}
+
+		// Palabra normal: la primera es el nombre del comando, el resto argumentos
+		if actual.Nombre == "" {
+			actual.Nombre = palabra
+		} else {
+			actual.Args = append(actual.Args, palabra)
+		}
+	}
+
+	if actual.Nombre == "" {
+		return nil, errorSintaxis("|")
+	}
+	tub.Comandos = append(tub.Comandos, actual)
+	return tub, nil
+}
+
+// analizarRedireccion reconoce si una palabra es una redirección. Si el
+// archivo va pegado al operador (">out.txt") se devuelve como destino.
+func analizarRedireccion(palabra string) (Redireccion, bool) {
+	for _, op := range operadoresRedireccion {
+		if !strings.HasPrefix(palabra, op.prefijo) {
+			continue
+		}
+		red := Redireccion{Descriptor: op.descriptor, Operador: op.operador}
+		if op.operador == ">&" {
+			// "2>&1" y "1>&2" / ">&2": el destino es el otro descriptor
+			red.Destino = op.prefijo[len(op.prefijo)-1:]
+			if palabra != op.prefijo {
+				return Redireccion{}, false
+			}
+		} else {
+			red.Destino = palabra[len(op.prefijo):]
+		}
+		return red, true
+	}
+	return Redireccion{}, false
+}
+
+// errSintaxis es el error base de todos los errores de sintaxis (estado 2)
+var errSintaxis = errors.New("error de sintaxis")
+
+// errorSintaxis construye el error de sintaxis para un símbolo inesperado
+func errorSintaxis(simbolo string) error {
+	return fmt.Errorf("%w cerca del símbolo inesperado '%s'", errSintaxis, simbolo)
+}
+
+// expandirPalabra sustituye las variables de una palabra por su valor.
+//
+// Formas soportadas:
+//   - $NOMBRE y ${NOMBRE}: variable del entorno de la shell (Shell.Env)
+//   - $?: estado del último comando
+//   - $$: PID de la shell
+//   - $!: PID del último trabajo en segundo plano
+//
+// Un "$" que no va seguido de un nombre válido se deja tal cual.
+//
+// Parámetros:
+//   - palabra: palabra a expandir
+//   - estricto: true con "set -u" (nounset); una variable sin definir es un error
+//
+// Retorna:
+//   - string: la palabra con las variables sustituidas
+//   - error: si estricto es true y alguna variable no está definida
+func (sh *Shell) expandirPalabra(palabra string, estricto bool) (string, error) {
+	if !strings.Contains(palabra, "$") {
+		return palabra, nil
+	}
+
+	var resultado strings.Builder
+	for i := 0; i < len(palabra); i++ {
+		if palabra[i] != '$' || i+1 >= len(palabra) {
+			resultado.WriteByte(palabra[i])
+			continue
+		}
+
+		// Variables especiales de un solo carácter
+		switch palabra[i+1] {
+		case '?':
+			resultado.WriteString(strconv.Itoa(sh.ultimoEstado))
+			i++
+			continue
+		case '$':
+			resultado.WriteString(strconv.Itoa(os.Getpid()))
+			i++
+			continue
+		case '!':
+			if sh.ultimoPIDSegundoPlano != 0 {
+				resultado.WriteString(strconv.Itoa(sh.ultimoPIDSegundoPlano))
+			} else if estricto {
+				return "", errors.New("!: variable sin definir")
+			}
+			i++
+			continue
+		}
+
+		// $NOMBRE o ${NOMBRE}
+		inicio, fin := i+1, i+1
+		llaves := palabra[inicio] == '{'
+		if llaves {
+			inicio++
+			cierre := strings.IndexByte(palabra[inicio:], '}')
+			if cierre < 0 {
+				return "", fmt.Errorf("%s: sustitución incorrecta", palabra)
+			}
+			fin = inicio + cierre
+		} else {
+			for fin < len(palabra) && esCaracterNombre(palabra[fin], fin == inicio) {
+				fin++
+			}
+		}
+
+		nombre := palabra[inicio:fin]
+		if nombre == "" || !esNombreVariable(nombre) {
+			if llaves {
+				return "", fmt.Errorf("%s: sustitución incorrecta", palabra)
+			}
+			resultado.WriteByte('$')
+			continue
+		}
+
+		valor, definida := sh.variable(nombre)
+		if !definida && estricto {
+			return "", fmt.Errorf("%s: variable sin definir", nombre)
+		}
+		resultado.WriteString(valor)
+
+		i = fin - 1
+		if llaves {
+			i = fin // Saltar también la llave de cierre
+		}
+	}
+	return resultado.String(), nil
+}
+
+// esCaracterNombre indica si c puede formar parte de un nombre de variable
+// (letras, dígitos y "_"; el primer carácter no puede ser un dígito)
+func esCaracterNombre(c byte, primero bool) bool {
+	switch {
+	case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return true
+	case c >= '0' && c <= '9':
+		return !primero
+	default:
+		return false
+	}
+}
+
+// esNombreVariable indica si el texto es un nombre de variable válido
+func esNombreVariable(nombre string) bool {
+	for i := 0; i < len(nombre); i++ {
+		if !esCaracterNombre(nombre[i], i == 0) {
+			return false
+		}
+	}
+	return nombre != ""
+}
@@ -0,0 +1,239 @@
+// Módulo builtins: Define la interfaz de los comandos internos y el registro
+// que los contiene. El registro decide qué comandos son internos y lo usan el
+// dispatcher, type, help y el completado de nombres
+package goshell
+
+import (
+	"context" // Para el contexto con el que se ejecuta cada comando
+	"fmt"     // Para mostrar la ayuda y las descripciones
+	"io"      // Para los flujos estándar de cada comando
+	"sort"    // Para listar los comandos en orden alfabético
+	"strings" // Para buscar comandos por prefijo
+	"sync"    // Para proteger el registro del acceso concurrente
+)
+
+// EntradaSalida contiene los flujos estándar con los que se ejecuta un
+// comando interno. Dentro de una tubería o con redirecciones son extremos de
+// tubería o archivos; en otro caso, los de la shell.
+type EntradaSalida struct {
+	Entrada io.Reader // Entrada estándar
+	Salida  io.Writer // Salida estándar
+	Error   io.Writer // Error estándar
+
+	// Contexto es el de la llamada a Run en curso. Al cancelarse se detienen
+	// los procesos externos que lance el comando. Si es nil, no se cancela.
+	Contexto context.Context
+}
+
+// contexto devuelve el contexto de los flujos, o context.Background si no
+// tienen ninguno
+func (es EntradaSalida) contexto() context.Context {
+	if es.Contexto == nil {
+		return context.Background()
+	}
+	return es.Contexto
+}
+
+// Builtin es un comando interno de la shell: se ejecuta dentro del propio
+// proceso y puede leer y modificar el estado de la shell.
+type Builtin interface {
+	// Nombre es el nombre con el que se invoca el comando (ej: "cd")
+	Nombre() string
+
+	// Uso es la línea de sinopsis que muestra help (ej: "cd [directorio]")
+	Uso() string
+
+	// Descripcion explica qué hace el comando, para "help nombre"
+	Descripcion() string
+
+	// Ejecutar ejecuta el comando con sus argumentos (sin el nombre). Debe
+	// escribir únicamente en los flujos recibidos, nunca en os.Stdout.
+	Ejecutar(sh *Shell, args []string, es EntradaSalida) error
+}
+
+// ComandoInterno implementa Builtin a partir de una función. Es la forma en
+// que se definen todos los comandos internos estándar.
+type ComandoInterno struct {
+	nombre      string
+	uso         string
+	descripcion string
+	funcion     func(sh *Shell, args []string, es EntradaSalida) error
+}
+
+// NuevoComandoInterno crea un comando interno a partir de su función.
+//
+// Parámetros:
+//   - nombre: nombre con el que se invoca el comando
+//   - uso: línea de sinopsis para help
+//   - descripcion: explicación para "help nombre"
+//   - funcion: implementación del comando
+func NuevoComandoInterno(nombre, uso, descripcion string, funcion func(sh *Shell, args []string, es EntradaSalida) error) *ComandoInterno {
+	return &ComandoInterno{nombre: nombre, uso: uso, descripcion: descripcion, funcion: funcion}
+}
+
+// Nombre implementa Builtin
+func (c *ComandoInterno) Nombre() string { return c.nombre }
+
+// Uso implementa Builtin
+func (c *ComandoInterno) Uso() string { return c.uso }
+
+// Descripcion implementa Builtin
+func (c *ComandoInterno) Descripcion() string { return c.descripcion }
+
+// Ejecutar implementa Builtin
+func (c *ComandoInterno) Ejecutar(sh *Shell, args []string, es EntradaSalida) error {
+	return c.funcion(sh, args, es)
+}
+
+// RegistroBuiltins guarda los comandos internos de una shell indexados por
+// nombre. Se consulta desde las goroutines de las tuberías, por eso se
+// protege con un mutex de lectura/escritura.
+type RegistroBuiltins struct {
+	mu       sync.RWMutex
+	builtins map[string]Builtin
+}
+
+// NuevoRegistroBuiltins crea un registro vacío
+func NuevoRegistroBuiltins() *RegistroBuiltins {
+	return &RegistroBuiltins{builtins: map[string]Builtin{}}
+}
+
+// Registrar agrega un comando interno al registro. Si ya existía uno con el
+// mismo nombre, lo reemplaza.
+func (r *RegistroBuiltins) Registrar(b Builtin) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.builtins[b.Nombre()] = b
+}
+
+// Buscar devuelve el comando interno con el nombre dado, si existe
+func (r *RegistroBuiltins) Buscar(nombre string) (Builtin, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	b, ok := r.builtins[nombre]
+	return b, ok
+}
+
+// Nombres devuelve los nombres de todos los comandos internos, ordenados
+func (r *RegistroBuiltins) Nombres() []string {
+	return r.Completar("")
+}
+
+// Completar devuelve, ordenados, los nombres de los comandos internos que
+// empiezan con el prefijo dado. Lo usan help y el completado de la línea.
+func (r *RegistroBuiltins) Completar(prefijo string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var nombres []string
+	for nombre := range r.builtins {
+		if strings.HasPrefix(nombre, prefijo) {
+			nombres = append(nombres, nombre)
+		}
+	}
+	sort.Strings(nombres)
+	return nombres
+}
+
+// comandosInternos son los comandos internos que NuevaShell registra
+var comandosInternos = []Builtin{
+	NuevoComandoInterno("builtin", "builtin comando-interno [argumentos ...]",
+		"Ejecuta un comando interno aunque exista un programa con el mismo nombre.",
+		ejecutarBuiltin),
+	NuevoComandoInterno("cd", "cd [-L|-P] [directorio | -]",
+		"Cambia el directorio de trabajo. Sin argumentos, cambia a $HOME; con -, al anterior ($OLDPWD). Busca los directorios relativos en $CDPATH; -P resuelve los enlaces simbólicos.",
+		ejecutarCd),
+	NuevoComandoInterno("command", "command [-vV] comando [argumentos ...]",
+		"Ejecuta el comando sin buscarlo entre las funciones ni los alias. Con -v o -V describe el comando en lugar de ejecutarlo.",
+		ejecutarCommand),
+	NuevoComandoInterno("dirs", "dirs [-clpv] [+N] [-N]",
+		"Muestra la pila de directorios. Con -v muestra un directorio por línea con su índice; con -c la vacía.",
+		ejecutarDirs),
+	NuevoComandoInterno("disown", "disown [-h] [-ar] [trabajo ...]",
+		"Retira trabajos de la tabla de trabajos. Con -h los mantiene, pero no les envía SIGHUP al salir.",
+		ejecutarDisown),
+	NuevoComandoInterno("exit", "exit [n]",
+		"Termina la shell con el estado n, o con el del último comando si se omite.",
+		ejecutarExit),
+	NuevoComandoInterno("hash", "hash [-r] [-p ruta] [-dt] [nombre ...]",
+		"Recuerda la ruta de los programas del PATH. Sin argumentos, lista los programas recordados y sus usos; con -r los olvida.",
+		ejecutarHash),
+	NuevoComandoInterno("help", "help [patrón ...]",
+		"Muestra la ayuda de los comandos internos cuyo nombre empieza con el patrón, o la lista de todos.",
+		ejecutarHelp),
+	NuevoComandoInterno("history", "history [-c] [-d posición] [n] o history -rw [archivo]",
+		"Muestra el historial de comandos (las últimas n entradas), borra todo (-c) o una entrada (-d), o lo guarda en (-w) o lee de (-r) $HISTFILE. Con $HISTTIMEFORMAT muestra la hora de cada entrada.",
+		ejecutarHistory),
+	NuevoComandoInterno("kill", "kill [-s señal | -n num | -señal] pid | %trabajo ... o kill -l [señal]",
+		"Envía una señal (SIGTERM por defecto) a procesos o trabajos. Con -l lista las señales.",
+		ejecutarKill),
+	NuevoComandoInterno("popd", "popd [+N | -N]",
+		"Quita un directorio de la pila. Sin argumentos quita el primero y cambia al siguiente.",
+		ejecutarPopd),
+	NuevoComandoInterno("pushd", "pushd [directorio | +N | -N]",
+		"Guarda el directorio actual en la pila y cambia a otro. Con +N o -N rota la pila; sin argumentos intercambia los dos primeros.",
+		ejecutarPushd),
+	NuevoComandoInterno("pwd", "pwd [-LP]",
+		"Muestra el directorio de trabajo. Por defecto (-L) muestra la ruta lógica que siguió cd; con -P, la física, con los enlaces simbólicos resueltos.",
+		ejecutarPwd),
+	NuevoComandoInterno("set", "set [-beCnux] [+beCnux] [-o opción] [+o opción]",
+		"Activa (-) o desactiva (+) opciones de la shell. Sin argumentos, lista las opciones.",
+		ejecutarSet),
+	NuevoComandoInterno("shopt", "shopt [-pqsu] [-o] [nombre ...]",
+		"Activa (-s) o desactiva (-u) opciones de la shell como autocd y cdspell. Sin -s ni -u, muestra su estado.",
+		ejecutarShopt),
+	NuevoComandoInterno("timeout", "timeout duración comando [argumentos ...]",
+		"Ejecuta un programa externo y lo termina si sigue en ejecución pasada la duración (ej: 10, 1.5s, 2m). Retorna 124 si se agotó el tiempo.",
+		ejecutarTimeout),
+	NuevoComandoInterno("trap", "trap [-lp] [[comando] señal ...]",
+		"Ejecuta el comando cuando la shell recibe alguna de las señales, o al salir (EXIT), tras un error (ERR) o antes de cada comando (DEBUG).",
+		ejecutarTrap),
+	NuevoComandoInterno("type", "type [-atp] nombre [nombre ...]",
+		"Indica cómo se interpretaría cada nombre si se usara como comando. Con -a muestra todas las posibilidades, con -t solo el tipo (builtin o file) y con -p solo la ruta del programa.",
+		ejecutarType),
+	NuevoComandoInterno("wait", "wait [-n] [trabajo ...]",
+		"Espera a que terminen los trabajos indicados, o todos, y retorna su estado. Con -n espera al primero que termine.",
+		ejecutarWait),
+	NuevoComandoInterno("z", "z [-l | -x] [patrón ...]",
+		"Cambia al directorio más usado y reciente que coincide con los patrones. Con -l lista los candidatos y con -x quita un directorio de la base de datos.",
+		ejecutarZ),
+}
+
+// ejecutarHelp implementa el comando interno 'help'.
+//
+// Formas soportadas:
+//   - help: lista todos los comandos internos con su sinopsis
+//   - help patrón...: muestra la ayuda de los comandos que empiezan con el patrón
+//
+// Parámetros:
+//   - sh: shell cuyos comandos internos se describen
+//   - args: slice de argumentos del comando help
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: nil si todos los patrones coincidieron con algún comando
+func ejecutarHelp(sh *Shell, args []string, es EntradaSalida) error {
+	if len(args) == 0 {
+		fmt.Fprintln(es.Salida, "Comandos internos de GoShell. Escribe 'help nombre' para ver la ayuda de uno.")
+		fmt.Fprintln(es.Salida)
+		for _, nombre := range sh.builtins.Nombres() {
+			b, _ := sh.builtins.Buscar(nombre)
+			fmt.Fprintf(es.Salida, "  %s\n", b.Uso())
+		}
+		return nil
+	}
+
+	for _, patron := range args {
+		nombres := sh.builtins.Completar(patron)
+		if len(nombres) == 0 {
+			return fmt.Errorf("help: no hay temas de ayuda que coincidan con '%s'", patron)
+		}
+		for _, nombre := range nombres {
+			b, _ := sh.builtins.Buscar(nombre)
+			fmt.Fprintf(es.Salida, "%s: %s\n    %s\n", nombre, b.Uso(), b.Descripcion())
+		}
+	}
+	return nil
+}
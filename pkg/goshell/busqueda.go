@@ -0,0 +1,234 @@
+// Módulo busqueda: Búsquedas en el historial desde el editor de línea: la
+// búsqueda incremental de readline (Ctrl-R hacia atrás, Ctrl-S hacia
+// adelante), que resalta lo encontrado, y la búsqueda por el comienzo de la
+// línea con las flechas, como history-beginning-search de zsh
+package goshell
+
+import (
+	"io"      // Para avisar con la campana
+	"strings" // Para armar el prompt y comparar prefijos
+	"unicode" // Para reconocer las letras que amplían la búsqueda
+)
+
+// busquedaHistorial es el estado de una búsqueda incremental en curso
+type busquedaHistorial struct {
+	texto      []rune      // Lo que se busca
+	haciaAtras bool        // true con Ctrl-R; false con Ctrl-S
+	inicio     int         // Entrada desde la que empezó la búsqueda
+	indice     int         // Entrada encontrada; la cantidad de entradas es la línea nueva
+	posicion   int         // Posición de lo encontrado dentro de la entrada
+	fallida    bool        // true si la última búsqueda no encontró nada
+	original   instantanea // Línea y cursor al empezar, para Ctrl-G
+}
+
+// iniciarBusqueda empieza una búsqueda incremental desde la línea en edición.
+// Si la búsqueda ya estaba en curso, busca la coincidencia siguiente en la
+// dirección indicada; con el texto vacío, repite el último texto buscado.
+func (ed *editorLinea) iniciarBusqueda(haciaAtras bool) {
+	if b := ed.busqueda; b != nil {
+		b.haciaAtras = haciaAtras
+		if len(b.texto) == 0 {
+			b.texto = append([]rune(nil), ed.ultimaBusqueda...)
+		}
+		if len(b.texto) > 0 {
+			ed.buscarIncremental(true)
+		}
+		return
+	}
+
+	total := ed.sh.historial.Cantidad()
+	ed.indiceHistorial = min(ed.indiceHistorial, total)
+	if ed.indiceHistorial == total {
+		ed.lineaNueva = append([]rune(nil), ed.buf...)
+	}
+	ed.busqueda = &busquedaHistorial{
+		haciaAtras: haciaAtras,
+		inicio:     ed.indiceHistorial,
+		indice:     ed.indiceHistorial,
+		posicion:   ed.pos,
+		original:   instantanea{append([]rune(nil), ed.buf...), ed.pos},
+	}
+}
+
+// atenderBusqueda atiende una tecla durante una búsqueda incremental.
+//
+// Comportamiento:
+//   - Las letras amplían el texto buscado y Retroceso lo acorta
+//   - Ctrl-R y Ctrl-S buscan la coincidencia anterior o la siguiente
+//   - Ctrl-G cancela la búsqueda y devuelve la línea original
+//   - Cualquier otra tecla termina la búsqueda con la línea encontrada y
+//     luego se atiende normalmente (Enter la ejecuta); ESC solo la termina
+//
+// Retorna:
+//   - bool: true si la tecla terminó la búsqueda y aún debe atenderse
+func (ed *editorLinea) atenderBusqueda(t tecla) bool {
+	b := ed.busqueda
+	switch {
+	case t.alt || t.especial != 0:
+		ed.terminarBusqueda()
+		return t.especial != teclaEscape
+	case t.r == ctrl('R'):
+		ed.iniciarBusqueda(true)
+	case t.r == ctrl('S'):
+		ed.iniciarBusqueda(false)
+	case t.r == ctrl('G'):
+		ed.buf, ed.pos = append([]rune(nil), b.original.buf...), b.original.pos
+		ed.busqueda = nil
+	case t.r == 0x7f || t.r == ctrl('H'):
+		// Acortar el texto y buscarlo de nuevo desde la línea original
+		if len(b.texto) > 0 {
+			b.texto = b.texto[:len(b.texto)-1]
+		}
+		b.indice, b.posicion = b.inicio, b.original.pos
+		ed.buf, ed.pos = append([]rune(nil), b.original.buf...), b.original.pos
+		if len(b.texto) > 0 {
+			ed.buscarIncremental(false)
+		} else {
+			b.fallida = false
+		}
+	case t.r >= 0x20 && unicode.IsPrint(t.r):
+		b.texto = append(b.texto, t.r)
+		ed.buscarIncremental(false)
+	default:
+		ed.terminarBusqueda()
+		return true
+	}
+	ed.refrescar()
+	return false
+}
+
+// terminarBusqueda deja en el editor la línea encontrada, con el cursor sobre
+// la coincidencia, y recuerda el texto para el próximo Ctrl-R
+func (ed *editorLinea) terminarBusqueda() {
+	b := ed.busqueda
+	if len(b.texto) > 0 {
+		ed.ultimaBusqueda = b.texto
+	}
+	if b.indice != ed.indiceHistorial {
+		ed.deshacer = append(ed.deshacer, b.original)
+	}
+	ed.indiceHistorial = b.indice
+	ed.busqueda = nil
+	ed.ultima = accionOtra
+}
+
+// buscarIncremental busca el texto de la búsqueda en el historial, desde la
+// coincidencia actual hacia atrás o hacia adelante. La línea que se estaba
+// escribiendo es la entrada más reciente. Si no encuentra nada, la línea no
+// cambia y suena la campana.
+//
+// Parámetros:
+//   - saltar: true para buscar la coincidencia siguiente a la actual; false
+//     si la actual sirve (el texto se amplió)
+func (ed *editorLinea) buscarIncremental(saltar bool) {
+	b := ed.busqueda
+	total := ed.sh.historial.Cantidad()
+	entrada := func(i int) []rune {
+		if i == b.inicio {
+			return b.original.buf
+		}
+		if i >= total {
+			return ed.lineaNueva
+		}
+		return []rune(ed.sh.historial.Entrada(i))
+	}
+
+	paso := 1
+	if b.haciaAtras {
+		paso = -1
+	}
+	desde := b.posicion
+	if saltar {
+		desde += paso
+	}
+	for i := b.indice; i >= 0 && i <= total; i += paso {
+		linea := entrada(i)
+		if i != b.indice {
+			desde = 0
+			if b.haciaAtras {
+				desde = len(linea)
+			}
+		}
+		if p := buscarRunas(linea, b.texto, desde, b.haciaAtras); p >= 0 {
+			b.indice, b.posicion, b.fallida = i, p, false
+			ed.buf, ed.pos = append([]rune(nil), linea...), p
+			return
+		}
+	}
+	b.fallida = true
+	io.WriteString(ed.sh.Stdout, "\a")
+}
+
+// buscarRunas busca un texto en una línea desde una posición: la última
+// aparición que empieza en desde o antes (hacia atrás) o la primera que
+// empieza en desde o después (hacia adelante). Retorna -1 si no aparece.
+func buscarRunas(linea, texto []rune, desde int, haciaAtras bool) int {
+	coincide := func(p int) bool {
+		return p+len(texto) <= len(linea) && string(linea[p:p+len(texto)]) == string(texto)
+	}
+	if haciaAtras {
+		for p := min(desde, len(linea)-len(texto)); p >= 0; p-- {
+			if coincide(p) {
+				return p
+			}
+		}
+		return -1
+	}
+	for p := max(desde, 0); p+len(texto) <= len(linea); p++ {
+		if coincide(p) {
+			return p
+		}
+	}
+	return -1
+}
+
+// promptBusqueda es el prompt que reemplaza al de la shell durante una
+// búsqueda incremental, como en readline: (reverse-i-search)`texto':
+func (ed *editorLinea) promptBusqueda() string {
+	b := ed.busqueda
+	var s strings.Builder
+	s.WriteString("(")
+	if b.fallida {
+		s.WriteString("failed ")
+	}
+	if b.haciaAtras {
+		s.WriteString("reverse-")
+	}
+	s.WriteString("i-search)`" + string(b.texto) + "': ")
+	return s.String()
+}
+
+// textoLinea devuelve la línea tal como se dibuja: durante una búsqueda
+// incremental, lo encontrado aparece en video inverso
+func (ed *editorLinea) textoLinea() string {
+	b := ed.busqueda
+	if b == nil || b.fallida || len(b.texto) == 0 || ed.pos+len(b.texto) > len(ed.buf) {
+		return string(ed.buf)
+	}
+	fin := ed.pos + len(b.texto)
+	return string(ed.buf[:ed.pos]) + "\x1b[7m" + string(ed.buf[ed.pos:fin]) + "\x1b[27m" + string(ed.buf[fin:])
+}
+
+// buscarPrefijo muestra la entrada anterior (delta -1) o siguiente (delta
+// +1) del historial que empieza con el texto escrito antes del cursor, como
+// history-beginning-search de zsh. Al pulsar la flecha varias veces seguidas
+// se sigue buscando con el mismo comienzo, hasta volver a la línea nueva;
+// sin texto escrito, las flechas recorren todo el historial.
+func (ed *editorLinea) buscarPrefijo(delta int) {
+	total := ed.sh.historial.Cantidad()
+	if ed.ultima != accionHistorial || ed.indiceHistorial >= total {
+		ed.prefijo = string(ed.buf[:ed.pos])
+	}
+	actual := string(ed.buf)
+	for i := min(ed.indiceHistorial, total) + delta; i >= 0 && i <= total; i += delta {
+		if i == total {
+			ed.mostrarEntrada(i)
+			return
+		}
+		if entrada := ed.sh.historial.Entrada(i); strings.HasPrefix(entrada, ed.prefijo) && entrada != actual {
+			ed.mostrarEntrada(i)
+			return
+		}
+	}
+	io.WriteString(ed.sh.Stdout, "\a")
+}
@@ -0,0 +1,153 @@
+// Módulo cancelación: Detiene los procesos externos cuando se cancela el
+// contexto con el que se ejecutan y contiene el comando interno timeout
+package goshell
+
+import (
+	"context" // Para el plazo de timeout
+	"errors"  // Para reconocer el plazo vencido y los procesos ya terminados
+	"fmt"     // Para los mensajes de error de timeout
+	"os"      // Para el error de proceso ya terminado
+	"os/exec" // Para el proceso externo que espera procesoExterno
+	"strconv" // Para las duraciones escritas en segundos
+	"syscall" // Para las señales SIGTERM y SIGKILL
+	"time"    // Para la espera entre señales y las duraciones
+)
+
+// esperaCancelacionPredeterminada es el valor inicial de
+// Shell.EsperaCancelacion
+const esperaCancelacionPredeterminada = 2 * time.Second
+
+// procesoExterno es un programa externo iniciado por la shell (ver
+// iniciarProceso). Su Wait además detiene el SIGKILL pendiente de una
+// cancelación cuando ya no tiene a quién enviarse.
+type procesoExterno struct {
+	*exec.Cmd
+
+	// detencion es el SIGKILL programado por cancelarProceso, o nil, y grupo
+	// el PGID al que se envía (0 si el proceso no tiene grupo propio). Los
+	// asigna Cmd.Cancel, que os/exec siempre termina antes de que Cmd.Wait
+	// retorne, por lo que Wait puede leerlos sin mutex
+	detencion *time.Timer
+	grupo     int
+}
+
+// Wait espera a que el proceso termine y detiene el SIGKILL pendiente si ya
+// no queda ningún proceso que pueda recibirlo. Mientras quede algún miembro
+// de su grupo (por ejemplo, un nieto que ignora SIGTERM) el SIGKILL sigue
+// programado y alcanzará al grupo completo.
+func (p *procesoExterno) Wait() error {
+	err := p.Cmd.Wait()
+	if p.detencion != nil && (p.grupo == 0 || enviarSenal(p.grupo, true, 0) != nil) {
+		p.detencion.Stop()
+	}
+	return err
+}
+
+// cancelarProceso detiene un proceso cuyo contexto se canceló: le envía
+// SIGTERM (a él o a todo su grupo) para que pueda terminar ordenadamente y,
+// pasado sh.EsperaCancelacion, SIGKILL. Se usa como exec.Cmd.Cancel.
+//
+// Las señales nunca se envían a un PID ya retirado con Wait, que podría
+// pertenecer a otro proceso (ver senalDetencion).
+//
+// Parámetros:
+//   - p: proceso a detener
+//   - grupo: PGID del grupo de procesos de p, o 0 si p no tiene un grupo
+//            propio
+//
+// Retorna:
+//   - *time.Timer: el SIGKILL programado (nil si ya no quedaba ningún
+//                  proceso que detener)
+//   - error: os.ErrProcessDone si ya no quedaba ningún proceso que detener,
+//            o el error al enviar SIGTERM
+func (sh *Shell) cancelarProceso(p *os.Process, grupo int) (*time.Timer, error) {
+	err := senalDetencion(p, grupo, syscall.SIGTERM)
+	if errors.Is(err, os.ErrProcessDone) {
+		return nil, err
+	}
+
+	// Los procesos del grupo que ignoren SIGTERM (o que lo hereden ignorado)
+	// se terminan igualmente, aunque el líder ya haya sido retirado; si ya no
+	// existen, el error se descarta
+	detencion := time.AfterFunc(sh.EsperaCancelacion, func() {
+		senalDetencion(p, grupo, syscall.SIGKILL)
+	})
+	return detencion, err
+}
+
+// senalDetencion envía una señal de cancelación a todo el grupo de procesos
+// grupo o, si es 0, solo a p.
+//
+// Al proceso se le envía a través de os.Process, que sabe si ya fue retirado
+// con Wait. El PGID de un grupo no puede reutilizarse mientras quede algún
+// miembro vivo, aunque el líder ya haya sido retirado; un grupo vacío se
+// trata como un proceso terminado.
+//
+// Retorna:
+//   - error: os.ErrProcessDone si ya no quedaba ningún proceso
+func senalDetencion(p *os.Process, grupo int, senal syscall.Signal) error {
+	if grupo == 0 {
+		return senalProceso(p, senal)
+	}
+	err := enviarSenal(grupo, true, senal)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+// ejecutarTimeout implementa el comando interno 'timeout': ejecuta un programa
+// externo con un plazo. Si el programa sigue en ejecución al vencer el plazo,
+// se detiene igual que al cancelar el contexto de Run (SIGTERM y luego
+// SIGKILL a su grupo de procesos) y el estado es 124, como en coreutils.
+//
+// Igual que el timeout de coreutils, el comando siempre es un programa del
+// PATH, aunque exista un comando interno con el mismo nombre.
+//
+// Parámetros:
+//   - sh: shell en la que se busca y ejecuta el programa
+//   - args: duración, programa y sus argumentos
+//   - es: flujos y contexto del comando
+//
+// Retorna:
+//   - error: el resultado del programa, EstadoSalida(124) si se agotó el plazo
+func ejecutarTimeout(sh *Shell, args []string, es EntradaSalida) error {
+	if len(args) < 2 {
+		return errors.New("timeout: uso: timeout duración comando [argumentos ...]")
+	}
+	plazo, err := parsearDuracion(args[0])
+	if err != nil {
+		return fmt.Errorf("timeout: %v", err)
+	}
+
+	// PASO 1: Derivar el contexto del programa del contexto del comando, para
+	// que cancelar Run también lo detenga. Una duración 0 no pone límite
+	padre := es.contexto()
+	ctx, cancelar := padre, context.CancelFunc(func() {})
+	if plazo > 0 {
+		ctx, cancelar = context.WithTimeout(padre, plazo)
+	}
+	defer cancelar()
+	es.Contexto = ctx
+
+	// PASO 2: Ejecutar el programa y esperar a que termine
+	err = sh.ejecutarPrograma(Comando{Nombre: args[1], Args: args[2:]}, es)
+
+	// PASO 3: Distinguir el plazo vencido de una cancelación del contexto padre
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && padre.Err() == nil {
+		return EstadoSalida(124)
+	}
+	return err
+}
+
+// parsearDuracion interpreta la duración de timeout: un número de segundos
+// (ej: "10", "0.5") o una duración de Go (ej: "1.5s", "2m", "500ms").
+func parsearDuracion(texto string) (time.Duration, error) {
+	if segundos, err := strconv.ParseFloat(texto, 64); err == nil && segundos >= 0 {
+		return time.Duration(segundos * float64(time.Second)), nil
+	}
+	if d, err := time.ParseDuration(texto); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("%s: duración inválida", texto)
+}
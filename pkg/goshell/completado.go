@@ -0,0 +1,192 @@
+// Módulo completado: Completa con Tab la palabra que está escribiendo el
+// usuario en el editor de línea: nombres de comandos (comandos internos y
+// programas del PATH) al comienzo del comando y rutas de archivos en el resto
+package goshell
+
+import (
+	"io"            // Para escribir en la terminal
+	"os"            // Para leer los directorios
+	"path/filepath" // Para separar el directorio de la ruta
+	"sort"          // Para ordenar las opciones
+	"strings"       // Para comparar prefijos
+	"unicode"       // Para separar las palabras
+)
+
+// completarPalabra busca las formas de completar la palabra que termina en el
+// cursor. La primera palabra de un comando (al comienzo de la línea o después
+// de un '|') se completa con los comandos internos y los programas del PATH;
+// las demás, y las que contienen una '/', con rutas de archivos.
+//
+// Parámetros:
+//   - buf: texto de la línea
+//   - pos: posición del cursor
+//
+// Retorna:
+//   - int: posición en buf donde empieza la palabra
+//   - []string: palabras completas que pueden reemplazarla, ordenadas; las
+//     rutas de directorios terminan en '/'
+func (sh *Shell) completarPalabra(buf []rune, pos int) (int, []string) {
+	inicio := pos
+	for inicio > 0 && !unicode.IsSpace(buf[inicio-1]) && buf[inicio-1] != '|' {
+		inicio--
+	}
+	palabra := string(buf[inicio:pos])
+
+	// ¿Es la palabra el nombre del comando?
+	anterior := inicio
+	for anterior > 0 && unicode.IsSpace(buf[anterior-1]) {
+		anterior--
+	}
+	esComando := anterior == 0 || buf[anterior-1] == '|'
+
+	if !esComando || strings.ContainsRune(palabra, '/') {
+		return inicio, sh.completarRuta(palabra)
+	}
+
+	vistos := map[string]bool{}
+	var opciones []string
+	agregar := func(nombre string) {
+		if strings.HasPrefix(nombre, palabra) && !vistos[nombre] {
+			vistos[nombre] = true
+			opciones = append(opciones, nombre)
+		}
+	}
+	for _, nombre := range sh.builtins.Completar(palabra) {
+		agregar(nombre)
+	}
+	for _, nombre := range sh.programasPath() {
+		agregar(nombre)
+	}
+	sort.Strings(opciones)
+	return inicio, opciones
+}
+
+// completarRuta busca los archivos y directorios cuyo nombre empieza con la
+// última parte de una ruta. Los archivos ocultos solo se ofrecen si la
+// palabra empieza con '.', y "~/" se busca en $HOME.
+func (sh *Shell) completarRuta(palabra string) []string {
+	dir, base := "", palabra
+	if i := strings.LastIndex(palabra, "/"); i >= 0 {
+		dir, base = palabra[:i+1], palabra[i+1:]
+	}
+
+	buscar := dir
+	if resto, ok := strings.CutPrefix(dir, "~/"); ok {
+		if home, ok := sh.variable("HOME"); ok {
+			buscar = filepath.Join(home, resto)
+		}
+	}
+	if buscar == "" {
+		buscar = "."
+	}
+	entradas, err := os.ReadDir(sh.rutaAbsoluta(filepath.FromSlash(buscar)))
+	if err != nil {
+		return nil
+	}
+
+	var opciones []string
+	for _, entrada := range entradas {
+		nombre := entrada.Name()
+		if !strings.HasPrefix(nombre, base) || (strings.HasPrefix(nombre, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		// Seguir los enlaces simbólicos para saber si llevan a un directorio
+		if info, err := os.Stat(filepath.Join(sh.rutaAbsoluta(filepath.FromSlash(buscar)), nombre)); err == nil && info.IsDir() {
+			nombre += "/"
+		}
+		opciones = append(opciones, dir+nombre)
+	}
+	return opciones
+}
+
+// prefijoComun devuelve el prefijo más largo que comparten todas las palabras
+func prefijoComun(palabras []string) string {
+	if len(palabras) == 0 {
+		return ""
+	}
+	comun := []rune(palabras[0])
+	for _, p := range palabras[1:] {
+		r := []rune(p)
+		n := 0
+		for n < len(comun) && n < len(r) && comun[n] == r[n] {
+			n++
+		}
+		comun = comun[:n]
+	}
+	return string(comun)
+}
+
+// completar atiende Tab: si hay una sola opción la escribe completa (con un
+// espacio detrás, salvo los directorios); si hay varias, escribe la parte que
+// tienen en común. Si no puede agregar nada, un segundo Tab seguido muestra
+// todas las opciones debajo de la línea, como en bash.
+func (ed *editorLinea) completar() {
+	inicio, opciones := ed.sh.completarPalabra(ed.buf, ed.pos)
+	if len(opciones) == 0 {
+		io.WriteString(ed.sh.Stdout, "\a")
+		return
+	}
+
+	palabra := string(ed.buf[inicio:ed.pos])
+	reemplazo := prefijoComun(opciones)
+	if len(opciones) == 1 && !strings.HasSuffix(reemplazo, "/") {
+		reemplazo += " "
+	}
+	if len(reemplazo) > len(palabra) {
+		ed.guardarDeshacer(accionCompletar)
+		ed.buf = append(ed.buf[:inicio], ed.buf[ed.pos:]...)
+		ed.pos = inicio
+		ed.insertar([]rune(reemplazo))
+		return
+	}
+
+	if ed.ultima != accionCompletar {
+		io.WriteString(ed.sh.Stdout, "\a")
+		return
+	}
+	ed.saltarLinea()
+	ed.mostrarOpciones(opciones)
+}
+
+// mostrarOpciones escribe las opciones de completado en columnas, ordenadas
+// de arriba hacia abajo como en ls. De las rutas solo se muestra el nombre.
+func (ed *editorLinea) mostrarOpciones(opciones []string) {
+	nombres := make([]string, len(opciones))
+	anchoMaximo := 0
+	for i, opcion := range opciones {
+		nombres[i] = opcion
+		if j := strings.LastIndex(strings.TrimSuffix(opcion, "/"), "/"); j >= 0 {
+			nombres[i] = opcion[j+1:]
+		}
+		anchoMaximo = max(anchoMaximo, anchoTexto(nombres[i]))
+	}
+
+	anchoColumna := anchoMaximo + 2
+	columnas := max(1, ed.ancho()/anchoColumna)
+	filas := (len(nombres) + columnas - 1) / columnas
+
+	var s strings.Builder
+	for f := 0; f < filas; f++ {
+		for c := 0; c < columnas; c++ {
+			i := c*filas + f
+			if i >= len(nombres) {
+				break
+			}
+			s.WriteString(nombres[i])
+			if c < columnas-1 && i+filas < len(nombres) {
+				s.WriteString(strings.Repeat(" ", anchoColumna-anchoTexto(nombres[i])))
+			}
+		}
+		s.WriteString("\r\n")
+	}
+	io.WriteString(ed.sh.Stdout, s.String())
+}
+
+// anchoTexto devuelve cuántas columnas ocupa un texto en la terminal
+func anchoTexto(texto string) int {
+	ancho := 0
+	for _, r := range texto {
+		ancho += anchoRune(r)
+	}
+	return ancho
+}
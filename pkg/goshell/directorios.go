@@ -0,0 +1,377 @@
+// Módulo directorios: Contiene los comandos internos cd y pwd y el cambio del
+// directorio de trabajo de la shell, con las variables PWD y OLDPWD, la
+// búsqueda en CDPATH, el tratamiento lógico o físico de los enlaces simbólicos
+// y las opciones autocd y cdspell
+package goshell
+
+import (
+	"errors"        // Para los errores de uso de cd
+	"fmt"           // Para mostrar el nuevo directorio
+	"io/fs"         // Para reconocer los directorios que no existen
+	"os"            // Para comprobar el destino y obtener el home
+	"path/filepath" // Para construir y resolver las rutas
+	"strings"       // Para reconocer las rutas que empiezan con . o ..
+	"syscall"       // Para el error de cd cuando el destino no es un directorio
+)
+
+// ejecutarCd implementa el comando interno 'cd' para cambiar el directorio de trabajo.
+//
+// Comportamiento:
+//   - Sin argumentos: cambia al directorio $HOME
+//   - cd -: vuelve al directorio anterior ($OLDPWD) y lo muestra
+//   - cd dir: cambia al directorio especificado; si es relativo y no empieza
+//     con . o .., se busca primero en los directorios de $CDPATH (y si se
+//     encuentra en uno de ellos se muestra la ruta completa)
+//   - cd -L dir (por defecto): sigue la ruta lógica; ".." quita el último
+//     componente del directorio actual aunque sea un enlace simbólico
+//   - cd -P dir: resuelve los enlaces simbólicos y usa la ruta física
+//   - Con shopt -s cdspell (solo en la shell interactiva), si el directorio no
+//     existe se corrigen los errores de un carácter en sus componentes y se
+//     muestra el directorio elegido
+//
+// Solo cambia el directorio de la shell (Shell.Dir), no el del proceso: así
+// varias shells pueden convivir en el mismo programa.
+//
+// Parámetros:
+//   - sh: shell cuyo directorio se cambia
+//   - args: slice de argumentos del comando cd
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: nil si el cambio fue exitoso, error si el directorio no existe o no es accesible
+func ejecutarCd(sh *Shell, args []string, es EntradaSalida) error {
+	// PASO 1: Interpretar las opciones; si aparecen las dos, vale la última
+	fisico := false
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+		opcion := args[0]
+		args = args[1:]
+		if opcion == "--" {
+			break
+		}
+		for _, letra := range opcion[1:] {
+			switch letra {
+			case 'L':
+				fisico = false
+			case 'P':
+				fisico = true
+			default:
+				return fmt.Errorf("cd: -%c: opción inválida\ncd: uso: cd [-L|-P] [directorio]", letra)
+			}
+		}
+	}
+	if len(args) > 1 {
+		return errors.New("cd: demasiados argumentos")
+	}
+
+	// PASO 2: Determinar el destino
+	// Si no se proporcionan argumentos, ir al directorio home
+	if len(args) == 0 {
+		home, ok := sh.variable("HOME")
+		if !ok {
+			// Sin $HOME en el entorno de la shell, usar el del usuario del
+			// proceso (en Windows el home no está en $HOME)
+			var err error
+			if home, err = os.UserHomeDir(); err != nil {
+				return errors.New("cd: HOME no está definido")
+			}
+		}
+		return sh.cambiarDirectorio(home, fisico)
+	}
+
+	destino := args[0]
+	if destino == "-" {
+		// Volver al directorio anterior y mostrarlo
+		anterior, ok := sh.variable("OLDPWD")
+		if !ok || anterior == "" {
+			return errors.New("cd: OLDPWD no está definido")
+		}
+		if err := sh.cambiarDirectorio(anterior, fisico); err != nil {
+			return err
+		}
+		fmt.Fprintln(es.Salida, sh.Dir)
+		return nil
+	}
+
+	// PASO 3: Buscar los destinos relativos en $CDPATH
+	if ruta, ok := sh.buscarEnCdpath(destino); ok {
+		if err := sh.cambiarDirectorio(ruta, fisico); err != nil {
+			return err
+		}
+		// Igual que bash, mostrar el directorio si no es el obvio
+		if filepath.Clean(ruta) != sh.rutaAbsoluta(destino) {
+			fmt.Fprintln(es.Salida, sh.Dir)
+		}
+		return nil
+	}
+
+	// Sin $CDPATH, el destino es relativo al directorio actual de la shell
+	err := sh.cambiarDirectorio(destino, fisico)
+
+	// PASO 4: Con cdspell, corregir los errores pequeños de escritura y
+	// mostrar el directorio elegido
+	if errors.Is(err, fs.ErrNotExist) && sh.interactiva && sh.opciones[optCdspell].Load() {
+		if corregido, ok := sh.corregirRuta(destino); ok && sh.cambiarDirectorio(corregido, fisico) == nil {
+			fmt.Fprintln(es.Salida, corregido)
+			return nil
+		}
+	}
+	return err
+}
+
+// corregirRuta corrige los errores pequeños de escritura de una ruta de
+// directorio, como la opción cdspell de bash: cada componente que no existe
+// se reemplaza por el subdirectorio más parecido, si difiere en un solo
+// carácter (uno de más, de menos, cambiado o dos intercambiados).
+//
+// Parámetros:
+//   - destino: ruta escrita por el usuario, absoluta o relativa
+//
+// Retorna:
+//   - string: la ruta corregida, escrita igual que destino (relativa si lo era)
+//   - bool: false si algún componente no tiene una corrección única
+func (sh *Shell) corregirRuta(destino string) (string, bool) {
+	componentes := strings.Split(filepath.ToSlash(destino), "/")
+	corregidos := make([]string, 0, len(componentes))
+	cambios := 0
+
+	for _, componente := range componentes {
+		// Directorio ya corregido en el que se busca el componente. La raíz,
+		// los separadores repetidos, . y .. se conservan
+		actual := "."
+		if len(corregidos) > 0 {
+			actual = filepath.FromSlash(strings.Join(corregidos, "/") + "/")
+		}
+		actual = sh.rutaAbsoluta(actual)
+		if componente == "" || componente == "." || componente == ".." {
+			corregidos = append(corregidos, componente)
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(actual, componente)); err == nil && info.IsDir() {
+			corregidos = append(corregidos, componente)
+			continue
+		}
+
+		// Buscar el subdirectorio más parecido; un empate no se corrige
+		entradas, err := os.ReadDir(actual)
+		if err != nil {
+			return "", false
+		}
+		elegido, empate := "", false
+		for _, entrada := range entradas {
+			if distanciaEdicion(componente, entrada.Name()) > 1 {
+				continue
+			}
+			if info, err := os.Stat(filepath.Join(actual, entrada.Name())); err != nil || !info.IsDir() {
+				continue
+			}
+			if elegido != "" {
+				empate = true
+			}
+			elegido = entrada.Name()
+		}
+		if elegido == "" || empate {
+			return "", false
+		}
+		corregidos = append(corregidos, elegido)
+		cambios++
+	}
+
+	if cambios == 0 {
+		return "", false
+	}
+	return filepath.FromSlash(strings.Join(corregidos, "/")), true
+}
+
+// cdAutomatico devuelve, con la opción autocd en la shell interactiva, el
+// comando que cambia al directorio escrito como nombre de comando. Como en
+// bash, se muestra el cd equivalente por la salida de errores. Si la opción
+// está desactivada, el nombre no es un directorio o existe un programa con
+// ese nombre, devuelve nil.
+//
+// Parámetros:
+//   - nombre: nombre del comando, ya expandido
+//
+// Retorna:
+//   - Builtin: el comando que ejecuta el cd, o nil
+func (sh *Shell) cdAutomatico(nombre string) Builtin {
+	if !sh.interactiva || !sh.opciones[optAutocd].Load() {
+		return nil
+	}
+	if info, err := os.Stat(sh.rutaAbsoluta(nombre)); err != nil || !info.IsDir() {
+		return nil
+	}
+	if _, err := sh.buscarPrograma(nombre); err == nil {
+		return nil
+	}
+
+	return NuevoComandoInterno(nombre, "", "", func(sh *Shell, args []string, es EntradaSalida) error {
+		fmt.Fprintf(es.Error, "cd -- %s\n", nombre)
+		return ejecutarCd(sh, []string{"--", nombre}, es)
+	})
+}
+
+// buscarEnCdpath busca un directorio relativo en los directorios de $CDPATH.
+// Los destinos absolutos o que empiezan con . o .. no se buscan. Un elemento
+// vacío de $CDPATH es el directorio actual.
+//
+// Retorna:
+//   - string: ruta del directorio encontrado, tal como se escribió (sin
+//     resolver "." ni "..")
+//   - bool: true si se encontró en algún directorio de $CDPATH
+func (sh *Shell) buscarEnCdpath(destino string) (string, bool) {
+	cdpath, ok := sh.variable("CDPATH")
+	if !ok || cdpath == "" || filepath.IsAbs(destino) {
+		return "", false
+	}
+	primero, _, _ := strings.Cut(filepath.ToSlash(destino), "/")
+	if primero == "." || primero == ".." {
+		return "", false
+	}
+
+	for _, dir := range filepath.SplitList(cdpath) {
+		if dir == "" {
+			dir = "."
+		}
+		// Sin limpiar la ruta: "enlace/.." solo existe si existe enlace, y
+		// con cd -P sus ".." se aplican sobre el directorio real
+		ruta := sh.rutaAbsoluta(dir) + string(filepath.Separator) + destino
+		if info, err := os.Stat(ruta); err == nil && info.IsDir() {
+			return ruta, true
+		}
+	}
+	return "", false
+}
+
+// cambiarDirectorio cambia el directorio de trabajo de la shell, después de
+// comprobar que el destino existe y es un directorio, y actualiza las
+// variables PWD (nuevo directorio) y OLDPWD (el anterior). En la shell
+// interactiva, además, registra la visita para el comando z.
+//
+// Parámetros:
+//   - destino: ruta absoluta o relativa al directorio actual de la shell
+//   - fisico: true para resolver los enlaces simbólicos (cd -P); false para
+//     conservar la ruta lógica, en la que ".." se aplica sobre el texto
+//
+// Retorna:
+//   - error: *os.PathError si el destino no existe o no es un directorio
+func (sh *Shell) cambiarDirectorio(destino string, fisico bool) error {
+	ruta := sh.rutaAbsoluta(destino)
+	if fisico {
+		real, err := sh.rutaFisica(destino)
+		if err != nil {
+			return &os.PathError{Op: "chdir", Path: destino, Err: errors.Unwrap(err)}
+		}
+		ruta = real
+	}
+	info, err := os.Stat(ruta)
+	if err != nil {
+		return &os.PathError{Op: "chdir", Path: destino, Err: errors.Unwrap(err)}
+	}
+	if !info.IsDir() {
+		return &os.PathError{Op: "chdir", Path: destino, Err: syscall.ENOTDIR}
+	}
+
+	anterior := sh.Dir
+	sh.Dir = ruta
+	sh.definirVariable("OLDPWD", anterior)
+	sh.definirVariable("PWD", ruta)
+	sh.registrarVisita(ruta)
+	return nil
+}
+
+// rutaFisica resuelve un destino de cd -P componente a componente: los
+// enlaces simbólicos se resuelven antes de aplicar cada "..", que lleva así
+// al directorio padre real. Si a/l es un enlace a b/c, a/l/.. es b y no a.
+//
+// Parámetros:
+//   - destino: ruta absoluta o relativa al directorio actual de la shell
+//
+// Retorna:
+//   - string: ruta absoluta del destino, sin enlaces simbólicos
+//   - error: si algún componente de la ruta no existe
+func (sh *Shell) rutaFisica(destino string) (string, error) {
+	// PASO 1: Resolver el punto de partida: la raíz o el directorio actual
+	base, resto := sh.Dir, destino
+	if filepath.IsAbs(destino) {
+		vol := filepath.VolumeName(destino)
+		base, resto = vol+string(filepath.Separator), destino[len(vol):]
+	} else if base == "" {
+		base, _ = os.Getwd()
+	}
+	ruta, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return "", err
+	}
+
+	// PASO 2: Avanzar un componente por vez sobre la ruta ya resuelta
+	for _, parte := range strings.Split(filepath.ToSlash(resto), "/") {
+		switch parte {
+		case "", ".":
+		case "..":
+			ruta = filepath.Dir(ruta)
+		default:
+			if ruta, err = filepath.EvalSymlinks(filepath.Join(ruta, parte)); err != nil {
+				return "", err
+			}
+		}
+	}
+	return ruta, nil
+}
+
+// ejecutarPwd implementa el comando interno 'pwd': muestra el directorio de
+// trabajo de la shell.
+//
+// Formas soportadas:
+//   - pwd o pwd -L: muestra la ruta lógica, la que siguió cd (con los enlaces
+//     simbólicos tal como se escribieron)
+//   - pwd -P: muestra la ruta física, con los enlaces simbólicos resueltos
+//
+// Si aparecen las dos opciones, vale la última, como en cd.
+//
+// Parámetros:
+//   - sh: shell cuyo directorio se muestra
+//   - args: opciones del comando
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: si una opción no es válida o el directorio ya no existe
+func ejecutarPwd(sh *Shell, args []string, es EntradaSalida) error {
+	fisico := false
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || len(arg) < 2 {
+			continue // bash ignora los argumentos que no son opciones
+		}
+		for _, letra := range arg[1:] {
+			switch letra {
+			case 'L':
+				fisico = false
+			case 'P':
+				fisico = true
+			default:
+				return fmt.Errorf("pwd: -%c: opción inválida\npwd: uso: pwd [-LP]", letra)
+			}
+		}
+	}
+
+	// Sin directorio propio, la shell usa el del proceso
+	dir := sh.Dir
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return fmt.Errorf("pwd: %v", err)
+		}
+	}
+	if fisico {
+		real, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return fmt.Errorf("pwd: error al obtener el directorio actual: %v", errors.Unwrap(err))
+		}
+		dir = real
+	}
+
+	fmt.Fprintln(es.Salida, dir)
+	return nil
+}
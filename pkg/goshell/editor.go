@@ -0,0 +1,816 @@
+// Módulo editor: Editor de línea del prompt interactivo. Pone la terminal en
+// modo crudo mientras el usuario escribe y atiende las teclas al estilo de
+// emacs y readline: movimiento del cursor, borrado por palabras, un anillo de
+// texto cortado (kill ring) y deshacer. Tiene en cuenta el ancho en pantalla
+// de cada carácter: los ideogramas ocupan dos columnas y las marcas
+// combinantes, ninguna
+package goshell
+
+import (
+	"bufio"   // Para leer las teclas de la entrada
+	"errors"  // Para la interrupción con Ctrl-C
+	"fmt"     // Para las secuencias de movimiento del cursor
+	"io"      // Para escribir en la terminal
+	"os"      // Para poner la terminal en modo crudo
+	"sort"    // Para buscar en la tabla de caracteres anchos
+	"strings" // Para armar cada redibujado en una sola escritura
+	"sync"    // Para restaurar la terminal una sola vez
+	"unicode" // Para clasificar los caracteres
+)
+
+// errInterrupcion indica que el usuario pulsó Ctrl-C mientras editaba: la
+// línea se descarta y el REPL muestra un prompt nuevo
+var errInterrupcion = errors.New("interrupción")
+
+// maximoAnillo es la cantidad de textos cortados que recuerda el anillo
+const maximoAnillo = 16
+
+// Teclas especiales, que llegan como secuencias de escape
+const (
+	teclaNinguna       = iota // Secuencia desconocida: se ignora
+	teclaEscape               // ESC solo, sin una secuencia detrás
+	teclaArriba               // Flecha arriba
+	teclaAbajo                // Flecha abajo
+	teclaDerecha              // Flecha derecha
+	teclaIzquierda            // Flecha izquierda
+	teclaInicio               // Inicio (Home)
+	teclaFin                  // Fin (End)
+	teclaSuprimir             // Suprimir (Delete)
+	teclaCtrlDerecha          // Ctrl + flecha derecha: una palabra adelante
+	teclaCtrlIzquierda        // Ctrl + flecha izquierda: una palabra atrás
+)
+
+// tecla es una pulsación ya decodificada
+type tecla struct {
+	r        rune // Carácter o código de control (ej: 1 para Ctrl-A)
+	alt      bool // true si llegó precedida de ESC (Alt o Meta)
+	especial int  // Una de las constantes tecla*, o 0 si es un carácter
+}
+
+// ctrl devuelve el código que envía la terminal para Ctrl más una letra
+func ctrl(letra byte) rune {
+	return rune(letra & 0x1f)
+}
+
+// accionEditor clasifica la última tecla atendida. Algunas teclas se
+// comportan distinto según la anterior: los cortes seguidos se juntan en el
+// anillo, Alt-Y solo sigue a un pegado y las letras escritas seguidas se
+// deshacen juntas
+type accionEditor int
+
+const (
+	accionOtra accionEditor = iota
+	accionInsertar
+	accionCortar
+	accionPegar
+	accionCompletar
+	accionHistorial // Flechas: las siguientes buscan con el mismo comienzo de línea
+)
+
+// instantanea es un estado de la línea guardado para deshacer
+type instantanea struct {
+	buf []rune
+	pos int
+}
+
+// punto es una posición en pantalla, relativa al comienzo del prompt
+type punto struct {
+	fila, col int
+}
+
+// editorLinea lee las líneas del prompt interactivo. Si la entrada no es una
+// terminal (o el sistema no tiene modo crudo), lee líneas completas sin editar.
+//
+// El editor procesa cada tecla con sh.salidaMu tomado, igual que quien escribe
+// los avisos de los trabajos: así un aviso puede cortar la línea en edición y
+// volver a dibujarla (ver Shell.redibujarPrompt).
+type editorLinea struct {
+	sh     *Shell
+	lector *bufio.Reader
+
+	// ancho devuelve las columnas de la terminal; se consulta en cada
+	// redibujado para seguir los cambios de tamaño de la ventana
+	ancho func() int
+
+	// restaurar devuelve la terminal a su modo original; es nil fuera de la
+	// edición. Se protege aparte porque también lo llama la limpieza de salida
+	terminalMu sync.Mutex
+	restaurar  func() error
+
+	// Línea en edición
+	editando   bool   // true desde que se muestra el prompt hasta Enter
+	buf        []rune // Texto de la línea
+	pos        int    // Posición del cursor en buf
+	filaCursor int    // Fila de pantalla del cursor, contada desde el prompt
+
+	// Estado entre teclas
+	anillo       [][]rune      // Textos cortados, el más reciente al final
+	pegado       [3]int        // Inicio y fin del último pegado y su índice en el anillo
+	deshacer     []instantanea // Estados anteriores de la línea
+	ultima       accionEditor  // Clase de la última tecla atendida
+	meta         bool          // true tras un ESC solo: la tecla siguiente lleva Alt
+	prefijoCtrlX bool          // true tras Ctrl-X, que inicia Ctrl-X Ctrl-U
+
+	// Recorrido del historial con las flechas (ver historial.go)
+	indiceHistorial int    // Entrada mostrada; la cantidad de entradas es la línea nueva
+	lineaNueva      []rune // Línea que se estaba escribiendo antes de recorrer el historial
+	pendiente       string // Texto con el que empieza la próxima línea (histverify)
+
+	// Búsquedas en el historial (ver busqueda.go)
+	busqueda       *busquedaHistorial // Búsqueda incremental en curso (nil si no hay)
+	ultimaBusqueda []rune             // Último texto buscado, que repite Ctrl-R sin texto
+	prefijo        string             // Comienzo de línea que buscan las flechas
+
+	// Estado del modo vi (ver editor_vi.go)
+	viNormal     bool    // true en modo normal; false en modo inserción
+	viComando    []rune  // Teclas del comando de modo normal en curso
+	viCambio     []tecla // Teclas del último cambio, que "." repite
+	viGrabando   bool    // true mientras el último cambio sigue en modo inserción
+	viRepitiendo bool    // true mientras "." repite el último cambio
+	viBusqueda   [2]rune // Última búsqueda con f, F, t o T y su carácter
+}
+
+// nuevoEditor crea el editor de línea de una shell, que lee de lector
+func (sh *Shell) nuevoEditor(lector *bufio.Reader) *editorLinea {
+	ed := &editorLinea{sh: sh, lector: lector, ancho: func() int { return 80 }}
+	if f, ok := sh.Stdin.(*os.File); ok {
+		ed.ancho = func() int { return anchoTerminal(f) }
+	}
+	return ed
+}
+
+// leerLinea lee una línea del usuario. En una terminal la edita en modo
+// crudo; en otro caso lee hasta el siguiente '\n'.
+//
+// Retorna:
+//   - string: la línea, con el '\n' final
+//   - error: io.EOF con Ctrl-D en una línea vacía (o al terminar la entrada),
+//            errInterrupcion con Ctrl-C, u otro error de lectura
+func (ed *editorLinea) leerLinea() (string, error) {
+	f, ok := ed.sh.Stdin.(*os.File)
+	if !ok || !esTerminal(f) {
+		return ed.lector.ReadString('\n')
+	}
+	restaurar, err := modoCrudo(f)
+	if err != nil {
+		return ed.lector.ReadString('\n')
+	}
+	ed.terminalMu.Lock()
+	ed.restaurar = restaurar
+	ed.terminalMu.Unlock()
+	defer ed.restaurarTerminal()
+
+	return ed.editar()
+}
+
+// restaurarTerminal devuelve la terminal a su modo original si el editor la
+// había puesto en modo crudo. Se llama al terminar cada línea y al salir de
+// la shell, por si la salida ocurre mientras se edita (ej: SIGHUP).
+func (ed *editorLinea) restaurarTerminal() {
+	ed.terminalMu.Lock()
+	defer ed.terminalMu.Unlock()
+	if ed.restaurar != nil {
+		ed.restaurar()
+		ed.restaurar = nil
+	}
+}
+
+// editar atiende las teclas hasta que el usuario acepta o descarta la línea
+func (ed *editorLinea) editar() (string, error) {
+	ed.sh.salidaMu.Lock()
+	ed.iniciarLinea()
+	ed.sh.salidaMu.Unlock()
+
+	for {
+		t, err := ed.leerTecla()
+		ed.sh.salidaMu.Lock()
+		if err != nil {
+			ed.editando = false
+			ed.sh.salidaMu.Unlock()
+			return string(ed.buf), err
+		}
+		linea, fin, err := ed.atenderTecla(t)
+		if fin {
+			ed.editando = false
+		}
+		ed.sh.salidaMu.Unlock()
+		if fin {
+			return linea, err
+		}
+	}
+}
+
+// iniciarLinea prepara el editor para una línea nueva, con el prompt ya
+// mostrado en pantalla
+func (ed *editorLinea) iniciarLinea() {
+	ed.editando = true
+	ed.buf, ed.pos = nil, 0
+	ed.deshacer = nil
+	ed.ultima = accionOtra
+	ed.meta, ed.prefijoCtrlX = false, false
+	ed.viNormal, ed.viComando, ed.viGrabando = false, nil, false
+	ed.indiceHistorial, ed.lineaNueva = ed.sh.historial.Cantidad(), nil
+	ed.busqueda = nil
+
+	puntos, _ := ed.ubicacion()
+	ed.filaCursor = puntos[len(puntos)-1].fila
+
+	// Una línea devuelta por histverify queda lista para editar
+	if ed.pendiente != "" {
+		ed.buf, ed.pendiente = []rune(ed.pendiente), ""
+		ed.pos = len(ed.buf)
+	}
+	ed.refrescar()
+}
+
+// leerTecla lee una tecla de la entrada y decodifica las secuencias de escape
+// de las flechas, Inicio, Fin, Suprimir y Alt. Un ESC sin nada detrás en el
+// búfer es un ESC solo: las terminales envían cada secuencia completa de una
+// vez.
+func (ed *editorLinea) leerTecla() (tecla, error) {
+	r, _, err := ed.lector.ReadRune()
+	if err != nil {
+		return tecla{}, err
+	}
+	if r != 0x1b {
+		return tecla{r: r}, nil
+	}
+	if ed.lector.Buffered() == 0 {
+		return tecla{especial: teclaEscape}, nil
+	}
+
+	siguiente, _, err := ed.lector.ReadRune()
+	if err != nil {
+		return tecla{}, err
+	}
+	switch siguiente {
+	case '[':
+		// CSI: parámetros (dígitos y ';') y una letra o '~' final
+		var parametros strings.Builder
+		for {
+			b, err := ed.lector.ReadByte()
+			if err != nil {
+				return tecla{}, err
+			}
+			if b >= 0x40 && b <= 0x7e {
+				return tecla{especial: teclaCsi(parametros.String(), b)}, nil
+			}
+			parametros.WriteByte(b)
+		}
+	case 'O':
+		// SS3: algunas terminales envían así las flechas, Inicio y Fin
+		b, err := ed.lector.ReadByte()
+		if err != nil {
+			return tecla{}, err
+		}
+		return tecla{especial: teclaCsi("", b)}, nil
+	default:
+		return tecla{r: siguiente, alt: true}, nil
+	}
+}
+
+// teclaCsi traduce una secuencia CSI (ESC [ parámetros final) a una tecla
+func teclaCsi(parametros string, final byte) int {
+	ctrlPulsado := strings.HasSuffix(parametros, ";5")
+	switch final {
+	case 'A':
+		return teclaArriba
+	case 'B':
+		return teclaAbajo
+	case 'C':
+		if ctrlPulsado {
+			return teclaCtrlDerecha
+		}
+		return teclaDerecha
+	case 'D':
+		if ctrlPulsado {
+			return teclaCtrlIzquierda
+		}
+		return teclaIzquierda
+	case 'H':
+		return teclaInicio
+	case 'F':
+		return teclaFin
+	case '~':
+		switch parametros {
+		case "1", "7":
+			return teclaInicio
+		case "4", "8":
+			return teclaFin
+		case "3":
+			return teclaSuprimir
+		}
+	}
+	return teclaNinguna
+}
+
+// atenderTecla aplica una tecla a la línea en edición y la vuelve a dibujar,
+// con los atajos de vi si la opción "set -o vi" está activa y con los de
+// emacs en otro caso.
+//
+// Retorna:
+//   - string: la línea aceptada, con el '\n' final
+//   - bool: true si la edición terminó (Enter, Ctrl-C o Ctrl-D en una línea vacía)
+//   - error: io.EOF o errInterrupcion, si la edición terminó por esas teclas
+func (ed *editorLinea) atenderTecla(t tecla) (string, bool, error) {
+	// Durante una búsqueda incremental las teclas son de la búsqueda
+	if ed.busqueda != nil && !ed.atenderBusqueda(t) {
+		return "", false, nil
+	}
+	if ed.sh.opciones[optVi].Load() {
+		return ed.atenderVi(t)
+	}
+	return ed.atenderEmacs(t)
+}
+
+// atenderEmacs aplica una tecla con los atajos de emacs (ver atenderTecla).
+// El modo inserción de vi también la usa para las teclas que no son propias.
+func (ed *editorLinea) atenderEmacs(t tecla) (string, bool, error) {
+	// ESC solo: la tecla siguiente se interpreta con Alt, como en readline
+	if t.especial == teclaEscape {
+		ed.meta = true
+		return "", false, nil
+	}
+	if ed.meta {
+		t.alt, ed.meta = true, false
+	}
+
+	// Ctrl-X Ctrl-U deshace, igual que Ctrl-_
+	if ed.prefijoCtrlX {
+		ed.prefijoCtrlX = false
+		if !t.alt && t.r == ctrl('U') {
+			ed.deshacerCambio()
+			ed.ultima = accionOtra
+			ed.refrescar()
+		}
+		return "", false, nil
+	}
+
+	accion := accionOtra
+	switch {
+	case t.alt:
+		switch t.r {
+		case 'b', 'B':
+			ed.pos = ed.palabraAtras(ed.pos)
+		case 'f', 'F':
+			ed.pos = ed.palabraAdelante(ed.pos)
+		case 'd', 'D':
+			ed.cortar(ed.pos, ed.palabraAdelante(ed.pos), false)
+			accion = accionCortar
+		case 0x7f, ctrl('H'):
+			ed.cortar(ed.palabraAtras(ed.pos), ed.pos, true)
+			accion = accionCortar
+		case 'y', 'Y':
+			if ed.ultima == accionPegar {
+				ed.rotarPegado()
+				accion = accionPegar
+			}
+		}
+
+	case t.especial != 0:
+		switch t.especial {
+		case teclaIzquierda:
+			ed.pos = ed.anterior(ed.pos)
+		case teclaDerecha:
+			ed.pos = ed.siguiente(ed.pos)
+		case teclaArriba:
+			ed.buscarPrefijo(-1)
+			accion = accionHistorial
+		case teclaAbajo:
+			ed.buscarPrefijo(1)
+			accion = accionHistorial
+		case teclaInicio:
+			ed.pos = 0
+		case teclaFin:
+			ed.pos = len(ed.buf)
+		case teclaCtrlIzquierda:
+			ed.pos = ed.palabraAtras(ed.pos)
+		case teclaCtrlDerecha:
+			ed.pos = ed.palabraAdelante(ed.pos)
+		case teclaSuprimir:
+			ed.borrar(ed.pos, ed.siguiente(ed.pos))
+		}
+
+	default:
+		switch t.r {
+		case '\r', '\n':
+			ed.pos = len(ed.buf)
+			ed.refrescar()
+			ed.saltarLinea()
+			return string(ed.buf) + "\n", true, nil
+		case ctrl('C'):
+			ed.pos = len(ed.buf)
+			ed.refrescar()
+			io.WriteString(ed.sh.Stdout, "^C")
+			ed.saltarLinea()
+			return "", true, errInterrupcion
+		case ctrl('D'):
+			if len(ed.buf) == 0 {
+				return "", true, io.EOF
+			}
+			ed.borrar(ed.pos, ed.siguiente(ed.pos))
+		case ctrl('A'):
+			ed.pos = 0
+		case ctrl('E'):
+			ed.pos = len(ed.buf)
+		case ctrl('B'):
+			ed.pos = ed.anterior(ed.pos)
+		case ctrl('F'):
+			ed.pos = ed.siguiente(ed.pos)
+		case ctrl('P'):
+			ed.moverHistorial(-1)
+		case ctrl('N'):
+			ed.moverHistorial(1)
+		case ctrl('R'):
+			ed.iniciarBusqueda(true)
+		case ctrl('S'):
+			ed.iniciarBusqueda(false)
+		case 0x7f, ctrl('H'):
+			ed.borrar(ed.anterior(ed.pos), ed.pos)
+		case ctrl('K'):
+			ed.cortar(ed.pos, len(ed.buf), false)
+			accion = accionCortar
+		case ctrl('U'):
+			ed.cortar(0, ed.pos, true)
+			accion = accionCortar
+		case ctrl('W'):
+			ed.cortar(ed.espacioAtras(ed.pos), ed.pos, true)
+			accion = accionCortar
+		case ctrl('Y'):
+			ed.pegar()
+			accion = accionPegar
+		case ctrl('T'):
+			ed.transponer()
+		case ctrl('_'):
+			ed.deshacerCambio()
+		case ctrl('X'):
+			ed.prefijoCtrlX = true
+		case ctrl('L'):
+			io.WriteString(ed.sh.Stdout, "\x1b[H\x1b[2J")
+			ed.filaCursor = 0
+		case '\t':
+			ed.completar()
+			accion = accionCompletar
+		default:
+			if t.r >= 0x20 && t.r != 0x7f && (unicode.IsPrint(t.r) || anchoRune(t.r) == 0) {
+				ed.guardarDeshacer(accionInsertar)
+				ed.insertar([]rune{t.r})
+				accion = accionInsertar
+			}
+		}
+	}
+
+	ed.ultima = accion
+	ed.refrescar()
+	return "", false, nil
+}
+
+// guardarDeshacer guarda el estado de la línea antes de modificarla. Las
+// letras escritas seguidas se deshacen juntas.
+func (ed *editorLinea) guardarDeshacer(accion accionEditor) {
+	if accion == accionInsertar && ed.ultima == accionInsertar {
+		return
+	}
+	ed.deshacer = append(ed.deshacer, instantanea{append([]rune(nil), ed.buf...), ed.pos})
+}
+
+// deshacerCambio vuelve la línea al estado anterior a la última modificación
+func (ed *editorLinea) deshacerCambio() {
+	if len(ed.deshacer) == 0 {
+		io.WriteString(ed.sh.Stdout, "\a")
+		return
+	}
+	anterior := ed.deshacer[len(ed.deshacer)-1]
+	ed.deshacer = ed.deshacer[:len(ed.deshacer)-1]
+	ed.buf, ed.pos = anterior.buf, anterior.pos
+}
+
+// insertar escribe texto en la posición del cursor y lo deja detrás del
+// texto. Quien lo llama guarda antes el estado para deshacer
+func (ed *editorLinea) insertar(texto []rune) {
+	ed.buf = append(ed.buf[:ed.pos], append(append([]rune(nil), texto...), ed.buf[ed.pos:]...)...)
+	ed.pos += len(texto)
+}
+
+// borrar quita el texto entre desde y hasta y deja el cursor en desde
+func (ed *editorLinea) borrar(desde, hasta int) {
+	if desde >= hasta {
+		return
+	}
+	ed.guardarDeshacer(accionOtra)
+	ed.buf = append(ed.buf[:desde], ed.buf[hasta:]...)
+	ed.pos = desde
+}
+
+// cortar quita el texto entre desde y hasta y lo guarda en el anillo. Los
+// cortes seguidos se juntan en un solo texto, delante o detrás del anterior
+// según la dirección del corte, como en readline.
+//
+// Parámetros:
+//   - desde, hasta: texto a cortar
+//   - haciaAtras: true si el corte es hacia la izquierda del cursor
+func (ed *editorLinea) cortar(desde, hasta int, haciaAtras bool) {
+	if desde >= hasta {
+		return
+	}
+	texto := append([]rune(nil), ed.buf[desde:hasta]...)
+	switch {
+	case ed.ultima == accionCortar && len(ed.anillo) > 0 && haciaAtras:
+		ed.anillo[len(ed.anillo)-1] = append(texto, ed.anillo[len(ed.anillo)-1]...)
+	case ed.ultima == accionCortar && len(ed.anillo) > 0:
+		ed.anillo[len(ed.anillo)-1] = append(ed.anillo[len(ed.anillo)-1], texto...)
+	default:
+		ed.anillo = append(ed.anillo, texto)
+		if len(ed.anillo) > maximoAnillo {
+			ed.anillo = ed.anillo[1:]
+		}
+	}
+	ed.borrar(desde, hasta)
+}
+
+// pegar inserta en el cursor el último texto cortado (Ctrl-Y)
+func (ed *editorLinea) pegar() {
+	if len(ed.anillo) == 0 {
+		return
+	}
+	indice := len(ed.anillo) - 1
+	inicio := ed.pos
+	ed.guardarDeshacer(accionPegar)
+	ed.insertar(ed.anillo[indice])
+	ed.pegado = [3]int{inicio, ed.pos, indice}
+}
+
+// rotarPegado reemplaza el texto recién pegado por el anterior del anillo
+// (Alt-Y después de Ctrl-Y o de otro Alt-Y)
+func (ed *editorLinea) rotarPegado() {
+	if len(ed.anillo) < 2 {
+		return
+	}
+	inicio, fin, indice := ed.pegado[0], ed.pegado[1], ed.pegado[2]
+	indice = (indice - 1 + len(ed.anillo)) % len(ed.anillo)
+
+	ed.guardarDeshacer(accionOtra)
+	texto := ed.anillo[indice]
+	ed.buf = append(ed.buf[:inicio], append(append([]rune(nil), texto...), ed.buf[fin:]...)...)
+	ed.pos = inicio + len(texto)
+	ed.pegado = [3]int{inicio, ed.pos, indice}
+}
+
+// transponer intercambia el carácter anterior al cursor con el del cursor y
+// avanza; al final de la línea intercambia los dos últimos (Ctrl-T)
+func (ed *editorLinea) transponer() {
+	if len(ed.buf) < 2 || ed.pos == 0 {
+		return
+	}
+	if ed.pos == len(ed.buf) {
+		ed.pos--
+	}
+	ed.guardarDeshacer(accionOtra)
+	ed.buf[ed.pos-1], ed.buf[ed.pos] = ed.buf[ed.pos], ed.buf[ed.pos-1]
+	ed.pos++
+}
+
+// anterior devuelve la posición del carácter anterior a pos, saltando las
+// marcas combinantes para tratar cada letra acentuada como una sola
+func (ed *editorLinea) anterior(pos int) int {
+	for pos > 0 {
+		pos--
+		if anchoRune(ed.buf[pos]) > 0 {
+			break
+		}
+	}
+	return pos
+}
+
+// siguiente devuelve la posición del carácter siguiente a pos, incluidas sus
+// marcas combinantes
+func (ed *editorLinea) siguiente(pos int) int {
+	if pos < len(ed.buf) {
+		pos++
+	}
+	for pos < len(ed.buf) && anchoRune(ed.buf[pos]) == 0 {
+		pos++
+	}
+	return pos
+}
+
+// esPalabra indica si un carácter forma parte de una palabra para Alt-B,
+// Alt-F y Alt-D: letras y dígitos, como en readline
+func esPalabra(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+// palabraAtras devuelve el comienzo de la palabra anterior a pos
+func (ed *editorLinea) palabraAtras(pos int) int {
+	for pos > 0 && !esPalabra(ed.buf[pos-1]) {
+		pos--
+	}
+	for pos > 0 && esPalabra(ed.buf[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+// palabraAdelante devuelve el final de la palabra siguiente a pos
+func (ed *editorLinea) palabraAdelante(pos int) int {
+	for pos < len(ed.buf) && !esPalabra(ed.buf[pos]) {
+		pos++
+	}
+	for pos < len(ed.buf) && esPalabra(ed.buf[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// espacioAtras devuelve el comienzo de la palabra anterior a pos, separando
+// las palabras solo por espacios (Ctrl-W, como unix-word-rubout)
+func (ed *editorLinea) espacioAtras(pos int) int {
+	for pos > 0 && unicode.IsSpace(ed.buf[pos-1]) {
+		pos--
+	}
+	for pos > 0 && !unicode.IsSpace(ed.buf[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+// textoPrompt devuelve el prompt que el editor dibuja delante de la línea.
+// En modo vi lo precede el modo activo, como show-mode-in-prompt de readline:
+// "(ins)" en modo inserción y "(cmd)" en modo normal.
+func (ed *editorLinea) textoPrompt() string {
+	if ed.busqueda != nil {
+		return ed.promptBusqueda()
+	}
+	if !ed.sh.opciones[optVi].Load() {
+		return ed.sh.promptActual
+	}
+	if ed.viNormal {
+		return "(cmd) " + ed.sh.promptActual
+	}
+	return "(ins) " + ed.sh.promptActual
+}
+
+// ubicacion calcula en qué fila y columna de la pantalla queda cada carácter
+// del prompt y de la línea.
+//
+// Retorna:
+//   - []punto: la posición de cada carácter y, al final, la del final del texto
+//   - int: índice en el resultado del primer carácter de la línea
+func (ed *editorLinea) ubicacion() ([]punto, int) {
+	var anchos []int
+	for _, r := range textoVisible(ed.textoPrompt()) {
+		anchos = append(anchos, anchoRune(r))
+	}
+	inicio := len(anchos)
+	for _, r := range ed.buf {
+		anchos = append(anchos, anchoRune(r))
+	}
+	return ubicar(anchos, ed.ancho()), inicio
+}
+
+// ubicar distribuye en filas de la pantalla caracteres de los anchos dados.
+// Un carácter que no cabe al final de una fila pasa entero a la siguiente,
+// como hacen las terminales con los caracteres anchos.
+//
+// Parámetros:
+//   - anchos: ancho en columnas de cada carácter
+//   - columnas: ancho de la terminal
+//
+// Retorna:
+//   - []punto: la posición de cada carácter y, al final, la siguiente libre
+func ubicar(anchos []int, columnas int) []punto {
+	puntos := make([]punto, len(anchos)+1)
+	fila, col := 0, 0
+	for i, a := range anchos {
+		if col+a > columnas && col > 0 {
+			fila, col = fila+1, 0
+		}
+		puntos[i] = punto{fila, col}
+		col += a
+	}
+	if col >= columnas {
+		fila, col = fila+1, 0
+	}
+	puntos[len(anchos)] = punto{fila, col}
+	return puntos
+}
+
+// refrescar vuelve a dibujar el prompt y la línea, y deja el cursor en su
+// posición. Todo se escribe de una vez para evitar parpadeos.
+func (ed *editorLinea) refrescar() {
+	puntos, inicio := ed.ubicacion()
+	fin := puntos[len(puntos)-1]
+	cursor := puntos[inicio+ed.pos]
+
+	var s strings.Builder
+	s.WriteString("\x1b[?25l") // Ocultar el cursor mientras se dibuja
+	if ed.filaCursor > 0 {
+		fmt.Fprintf(&s, "\x1b[%dA", ed.filaCursor)
+	}
+	s.WriteString("\r\x1b[J")
+	s.WriteString(ed.textoPrompt())
+	s.WriteString(ed.textoLinea())
+
+	// Si el texto llena exactamente la última fila, la terminal deja el
+	// cursor en la última columna: pasar a la fila siguiente
+	if fin.col == 0 && fin.fila > 0 {
+		s.WriteString("\r\n")
+	}
+	if subir := fin.fila - cursor.fila; subir > 0 {
+		fmt.Fprintf(&s, "\x1b[%dA", subir)
+	}
+	s.WriteString("\r")
+	if cursor.col > 0 {
+		fmt.Fprintf(&s, "\x1b[%dC", cursor.col)
+	}
+	s.WriteString("\x1b[?25h")
+
+	ed.filaCursor = cursor.fila
+	io.WriteString(ed.sh.Stdout, s.String())
+}
+
+// saltarLinea lleva el cursor a una línea nueva debajo del texto en edición,
+// para escribir algo sin tapar la línea (al aceptarla, al mostrar un aviso o
+// las opciones del completado)
+func (ed *editorLinea) saltarLinea() {
+	puntos, _ := ed.ubicacion()
+	fin := puntos[len(puntos)-1]
+
+	var s strings.Builder
+	if bajar := fin.fila - ed.filaCursor; bajar > 0 {
+		fmt.Fprintf(&s, "\x1b[%dB", bajar)
+	}
+	if fin.col == 0 && fin.fila > 0 {
+		s.WriteString("\r") // refrescar ya pasó a la fila siguiente
+	} else {
+		s.WriteString("\r\n")
+	}
+	ed.filaCursor = 0
+	io.WriteString(ed.sh.Stdout, s.String())
+}
+
+// textoVisible quita de un texto las secuencias de escape ANSI (los colores
+// del prompt), que no ocupan lugar en pantalla
+func textoVisible(texto string) string {
+	var s strings.Builder
+	enEscape := false
+	for _, r := range texto {
+		switch {
+		case enEscape:
+			// La secuencia termina con una letra (ej: "m" en "\x1b[32m")
+			if r >= '@' && r <= '~' && r != '[' {
+				enEscape = false
+			}
+		case r == 0x1b:
+			enEscape = true
+		default:
+			s.WriteRune(r)
+		}
+	}
+	return s.String()
+}
+
+// caracteresAnchos son los rangos de caracteres que ocupan dos columnas en la
+// terminal: ideogramas, hangul, kana, formas de ancho completo y emoji (una
+// aproximación de los anchos W y F de Unicode East Asian Width)
+var caracteresAnchos = [][2]rune{
+	{0x1100, 0x115F}, {0x231A, 0x231B}, {0x2329, 0x232A}, {0x23E9, 0x23EC},
+	{0x23F0, 0x23F0}, {0x23F3, 0x23F3}, {0x25FD, 0x25FE}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267F, 0x267F}, {0x2693, 0x2693}, {0x26A1, 0x26A1},
+	{0x26AA, 0x26AB}, {0x26BD, 0x26BE}, {0x26C4, 0x26C5}, {0x26CE, 0x26CE},
+	{0x26D4, 0x26D4}, {0x26EA, 0x26EA}, {0x26F2, 0x26F3}, {0x26F5, 0x26F5},
+	{0x26FA, 0x26FA}, {0x26FD, 0x26FD}, {0x2705, 0x2705}, {0x270A, 0x270B},
+	{0x2728, 0x2728}, {0x274C, 0x274C}, {0x274E, 0x274E}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27B0, 0x27B0}, {0x27BF, 0x27BF},
+	{0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55}, {0x2E80, 0x303E},
+	{0x3041, 0x33FF}, {0x3400, 0x4DBF}, {0x4E00, 0x9FFF}, {0xA000, 0xA4CF},
+	{0xA960, 0xA97F}, {0xAC00, 0xD7A3}, {0xF900, 0xFAFF}, {0xFE10, 0xFE19},
+	{0xFE30, 0xFE6F}, {0xFF00, 0xFF60}, {0xFFE0, 0xFFE6}, {0x16FE0, 0x16FE4},
+	{0x17000, 0x18CFF}, {0x1B000, 0x1B2FF}, {0x1F004, 0x1F004}, {0x1F0CF, 0x1F0CF},
+	{0x1F18E, 0x1F18E}, {0x1F191, 0x1F19A}, {0x1F200, 0x1F251}, {0x1F300, 0x1F320},
+	{0x1F32D, 0x1F335}, {0x1F337, 0x1F37C}, {0x1F37E, 0x1F393}, {0x1F3A0, 0x1F3CA},
+	{0x1F3CF, 0x1F3D3}, {0x1F3E0, 0x1F3F0}, {0x1F3F4, 0x1F3F4}, {0x1F3F8, 0x1F43E},
+	{0x1F440, 0x1F440}, {0x1F442, 0x1F4FC}, {0x1F4FF, 0x1F53D}, {0x1F54B, 0x1F54E},
+	{0x1F550, 0x1F567}, {0x1F57A, 0x1F57A}, {0x1F595, 0x1F596}, {0x1F5A4, 0x1F5A4},
+	{0x1F5FB, 0x1F64F}, {0x1F680, 0x1F6C5}, {0x1F6CC, 0x1F6CC}, {0x1F6D0, 0x1F6D2},
+	{0x1F6D5, 0x1F6D7}, {0x1F6DC, 0x1F6DF}, {0x1F6EB, 0x1F6EC}, {0x1F6F4, 0x1F6FC},
+	{0x1F7E0, 0x1F7EB}, {0x1F7F0, 0x1F7F0}, {0x1F90C, 0x1F93A}, {0x1F93C, 0x1F945},
+	{0x1F947, 0x1F9FF}, {0x1FA70, 0x1FAFF}, {0x20000, 0x2FFFD}, {0x30000, 0x3FFFD},
+}
+
+// anchoRune devuelve cuántas columnas ocupa un carácter en la terminal: 0 para
+// las marcas combinantes y los caracteres de formato (ej: el acento de una
+// "é" escrita en dos partes), 2 para los caracteres anchos y 1 para el resto
+func anchoRune(r rune) int {
+	if r == 0x200B || (r >= 0x1160 && r <= 0x11FF) ||
+		unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	i := sort.Search(len(caracteresAnchos), func(i int) bool {
+		return caracteresAnchos[i][1] >= r
+	})
+	if i < len(caracteresAnchos) && caracteresAnchos[i][0] <= r {
+		return 2
+	}
+	return 1
+}
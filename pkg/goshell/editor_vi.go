@@ -0,0 +1,680 @@
+// Módulo editor_vi: Atajos de vi para el editor de línea (set -o vi). La
+// línea empieza en modo inserción, que se comporta como el modo emacs, y ESC
+// pasa al modo normal, con los movimientos, operadores y cuentas de vi. El
+// prompt muestra el modo activo
+package goshell
+
+import (
+	"fmt"     // Para los errores del editor externo
+	"io"      // Para escribir en la terminal
+	"os"      // Para el archivo temporal y volver al modo crudo
+	"os/exec" // Para ejecutar el editor externo (tecla v)
+	"strconv" // Para reescribir la cuenta de "."
+	"strings" // Para separar el comando del editor externo
+	"unicode" // Para clasificar los caracteres
+)
+
+// Resultado de interpretar las teclas acumuladas de un comando de modo normal
+const (
+	viIncompleto = iota // Faltan teclas (ej: "d" espera un movimiento)
+	viHecho             // El comando se ejecutó
+	viInvalido          // El comando no existe o no pudo ejecutarse
+)
+
+// abreviaturasVi son los comandos de modo normal que equivalen a un operador
+// con un movimiento
+var abreviaturasVi = map[rune]string{
+	'x': "dl",
+	'X': "dh",
+	's': "cl",
+	'S': "cc",
+	'D': "d$",
+	'C': "c$",
+	'Y': "yy",
+}
+
+// atenderVi aplica una tecla con los atajos de vi (ver atenderTecla).
+//
+// Comportamiento:
+//   - En modo inserción, ESC (o una tecla con Alt) pasa al modo normal y el
+//     resto de las teclas se atiende como en modo emacs
+//   - En modo normal, las teclas se acumulan hasta formar un comando completo
+//     ([cuenta] comando, o [cuenta] operador [cuenta] movimiento), que se
+//     ejecuta entonces; Enter, Ctrl-C, Ctrl-D y Ctrl-L funcionan igual que
+//     en modo emacs
+func (ed *editorLinea) atenderVi(t tecla) (string, bool, error) {
+	if !ed.viNormal {
+		if t.especial != teclaEscape && !t.alt {
+			if !terminaLinea(t) {
+				ed.grabarVi(t)
+			}
+			return ed.atenderEmacs(t)
+		}
+		// ESC termina la inserción; Alt-x llega como ESC seguido de x
+		ed.grabarVi(tecla{especial: teclaEscape})
+		ed.viGrabando = false
+		ed.modoNormal()
+		if !t.alt {
+			ed.refrescar()
+			return "", false, nil
+		}
+		t.alt = false
+	}
+
+	// Ctrl-R y Ctrl-S buscan en el historial también en modo normal
+	if !t.alt && t.especial == 0 && (t.r == ctrl('R') || t.r == ctrl('S')) {
+		ed.viComando = nil
+		ed.iniciarBusqueda(t.r == ctrl('R'))
+		ed.refrescar()
+		return "", false, nil
+	}
+
+	if terminaLinea(t) || (t.especial == 0 && t.r == ctrl('L')) {
+		ed.viComando = nil
+		if t.r == ctrl('D') && len(ed.buf) > 0 {
+			return "", false, nil
+		}
+		return ed.atenderEmacs(t)
+	}
+
+	// Las flechas y las teclas de edición equivalen a comandos de vi
+	r := t.r
+	switch t.especial {
+	case teclaNinguna:
+	case teclaIzquierda:
+		r = 'h'
+	case teclaDerecha:
+		r = 'l'
+	case teclaArriba:
+		r = 'k'
+	case teclaAbajo:
+		r = 'j'
+	case teclaInicio:
+		r = '0'
+	case teclaFin:
+		r = '$'
+	case teclaSuprimir:
+		r = 'x'
+	case teclaCtrlIzquierda:
+		r = 'b'
+	case teclaCtrlDerecha:
+		r = 'w'
+	default:
+		return "", false, nil
+	}
+
+	// v no lleva cuenta: abre la línea en el editor externo
+	if len(ed.viComando) == 0 && r == 'v' {
+		return ed.editarExterno()
+	}
+
+	ed.viComando = append(ed.viComando, r)
+	switch ed.ejecutarVi(ed.viComando) {
+	case viIncompleto:
+		return "", false, nil
+	case viInvalido:
+		io.WriteString(ed.sh.Stdout, "\a")
+	}
+	ed.viComando = nil
+	if ed.viNormal {
+		ed.ultima = accionOtra
+		ed.ajustarCursorVi()
+	}
+	ed.refrescar()
+	return "", false, nil
+}
+
+// terminaLinea indica si una tecla acepta o descarta la línea (Enter, Ctrl-C
+// y Ctrl-D), que en modo vi se atienden igual que en modo emacs
+func terminaLinea(t tecla) bool {
+	if t.alt || t.especial != 0 {
+		return false
+	}
+	return t.r == '\r' || t.r == '\n' || t.r == ctrl('C') || t.r == ctrl('D')
+}
+
+// modoNormal pasa al modo normal. Como en vi, el cursor retrocede un
+// carácter al salir del modo inserción
+func (ed *editorLinea) modoNormal() {
+	ed.viNormal = true
+	ed.ultima = accionOtra
+	if ed.pos > 0 {
+		ed.pos = ed.anterior(ed.pos)
+	}
+}
+
+// modoInsercion pasa al modo inserción. Todo lo que se escriba hasta volver
+// al modo normal se deshace junto con el comando que inició la inserción.
+//
+// Parámetros:
+//   - guardar: true si el comando no modificó la línea y hay que guardar el
+//              estado para deshacer (i, a, I, A); los operadores ya lo hicieron
+func (ed *editorLinea) modoInsercion(guardar bool) {
+	if guardar {
+		ed.guardarDeshacer(accionOtra)
+	}
+	ed.viNormal = false
+	ed.ultima = accionInsertar
+}
+
+// ajustarCursorVi deja el cursor sobre un carácter: en modo normal no puede
+// quedar detrás del último, como en vi
+func (ed *editorLinea) ajustarCursorVi() {
+	if len(ed.buf) > 0 && ed.pos >= len(ed.buf) {
+		ed.pos = ed.anterior(len(ed.buf))
+	}
+}
+
+// grabarVi agrega una tecla de modo inserción al último cambio, para que "."
+// repita también el texto escrito
+func (ed *editorLinea) grabarVi(t tecla) {
+	if ed.viGrabando && !ed.viRepitiendo {
+		ed.viCambio = append(ed.viCambio, t)
+	}
+}
+
+// finCambioVi recuerda las teclas de un comando que modificó la línea, para
+// repetirlo con ".". Si el comando pasó al modo inserción, la grabación
+// continúa con lo que se escriba hasta ESC.
+func (ed *editorLinea) finCambioVi(teclas []rune) {
+	if ed.viRepitiendo {
+		return
+	}
+	ed.viCambio = ed.viCambio[:0]
+	for _, r := range teclas {
+		ed.viCambio = append(ed.viCambio, tecla{r: r})
+	}
+	ed.viGrabando = !ed.viNormal
+}
+
+// leerCuentaVi lee la cuenta que puede preceder a un comando o a un
+// movimiento. Un '0' inicial no es una cuenta sino el movimiento al comienzo.
+//
+// Retorna:
+//   - int: la cuenta, o 1 si no hay
+//   - bool: true si había una cuenta
+func leerCuentaVi(teclas []rune, i *int) (int, bool) {
+	cuenta, hay := 0, false
+	for *i < len(teclas) && teclas[*i] >= '0' && teclas[*i] <= '9' && (hay || teclas[*i] != '0') {
+		cuenta = min(cuenta*10+int(teclas[*i]-'0'), 9999)
+		hay = true
+		*i++
+	}
+	if !hay {
+		return 1, false
+	}
+	return cuenta, true
+}
+
+// ejecutarVi interpreta las teclas acumuladas de un comando de modo normal y
+// lo ejecuta si está completo.
+//
+// Formas soportadas:
+//   - [n]movimiento: h l w W b B e E 0 ^ $ f F t T ; , y espacio
+//   - [n]operador[n]movimiento, con los operadores d (borrar), c (cambiar)
+//     e y (copiar); el operador repetido (dd, cc, yy) abarca toda la línea
+//   - [n]x X s S D C Y: abreviaturas de dl dh cl cc d$ c$ yy
+//   - i a I A: pasan al modo inserción en el cursor, detrás de él, al
+//     comienzo o al final de la línea
+//   - [n]p [n]P: pegan el último texto borrado o copiado detrás o delante
+//     del cursor
+//   - [n]r carácter, [n]~: reemplazan caracteres o cambian su capitalización
+//   - [n]u: deshace
+//   - [n]k [n]j (o - +): muestran la entrada anterior o siguiente del historial
+//   - [n].: repite el último cambio, con la cuenta nueva si se indica
+//
+// Retorna:
+//   - int: viIncompleto, viHecho o viInvalido
+func (ed *editorLinea) ejecutarVi(teclas []rune) int {
+	i := 0
+	cuenta, hayCuenta := leerCuentaVi(teclas, &i)
+	if i >= len(teclas) {
+		return viIncompleto
+	}
+	comando := teclas[i]
+	i++
+
+	switch comando {
+	case 'd', 'c', 'y':
+		cuenta2, _ := leerCuentaVi(teclas, &i)
+		if i >= len(teclas) {
+			return viIncompleto
+		}
+		movimiento := teclas[i]
+		i++
+
+		desde, hasta := 0, len(ed.buf)
+		if movimiento != comando {
+			var argumento rune
+			if necesitaArgumentoVi(movimiento) {
+				if i >= len(teclas) {
+					return viIncompleto
+				}
+				argumento = teclas[i]
+			}
+			// cw cambia hasta el final de la palabra, sin el espacio que sigue
+			if comando == 'c' && (movimiento == 'w' || movimiento == 'W') &&
+				ed.pos < len(ed.buf) && !unicode.IsSpace(ed.buf[ed.pos]) {
+				movimiento += 'e' - 'w'
+			}
+			destino, inclusivo, ok := ed.movimientoVi(movimiento, cuenta*cuenta2, argumento)
+			if !ok {
+				return viInvalido
+			}
+			desde, hasta = min(ed.pos, destino), max(ed.pos, destino)
+			if inclusivo {
+				hasta = ed.siguiente(hasta)
+			}
+		}
+		ed.operarVi(comando, desde, hasta)
+		if comando != 'y' {
+			ed.finCambioVi(teclas)
+		}
+		return viHecho
+
+	case 'x', 'X', 's', 'S', 'D', 'C', 'Y':
+		equivalente := append(append([]rune(nil), teclas[:i-1]...), []rune(abreviaturasVi[comando])...)
+		return ed.ejecutarVi(equivalente)
+
+	case 'i', 'a', 'I', 'A':
+		switch comando {
+		case 'a':
+			ed.pos = ed.siguiente(ed.pos)
+		case 'I':
+			ed.pos = ed.primerNoBlanco()
+		case 'A':
+			ed.pos = len(ed.buf)
+		}
+		ed.modoInsercion(true)
+		ed.finCambioVi(teclas)
+		return viHecho
+
+	case 'p', 'P':
+		if len(ed.anillo) == 0 {
+			return viInvalido
+		}
+		ed.guardarDeshacer(accionOtra)
+		if comando == 'p' {
+			ed.pos = ed.siguiente(ed.pos)
+		}
+		for range cuenta {
+			ed.insertar(ed.anillo[len(ed.anillo)-1])
+		}
+		ed.pos = ed.anterior(ed.pos)
+		ed.finCambioVi(teclas)
+		return viHecho
+
+	case 'r':
+		if i >= len(teclas) {
+			return viIncompleto
+		}
+		if ed.pos+cuenta > len(ed.buf) || !unicode.IsPrint(teclas[i]) {
+			return viInvalido
+		}
+		ed.guardarDeshacer(accionOtra)
+		for k := range cuenta {
+			ed.buf[ed.pos+k] = teclas[i]
+		}
+		ed.pos += cuenta - 1
+		ed.finCambioVi(teclas)
+		return viHecho
+
+	case '~':
+		if len(ed.buf) == 0 {
+			return viInvalido
+		}
+		ed.guardarDeshacer(accionOtra)
+		for k := 0; k < cuenta && ed.pos < len(ed.buf); k++ {
+			if r := ed.buf[ed.pos]; unicode.IsUpper(r) {
+				ed.buf[ed.pos] = unicode.ToLower(r)
+			} else {
+				ed.buf[ed.pos] = unicode.ToUpper(r)
+			}
+			ed.pos++
+		}
+		ed.finCambioVi(teclas)
+		return viHecho
+
+	case 'u':
+		for range cuenta {
+			ed.deshacerCambio()
+		}
+		return viHecho
+
+	case 'k', '-', 'j', '+':
+		delta := -cuenta
+		if comando == 'j' || comando == '+' {
+			delta = cuenta
+		}
+		if !ed.moverHistorial(delta) {
+			return viHecho // moverHistorial ya avisó con la campana
+		}
+		ed.pos = ed.primerNoBlanco()
+		return viHecho
+
+	case '.':
+		return ed.repetirCambioVi(cuenta, hayCuenta)
+	}
+
+	// El resto son movimientos
+	var argumento rune
+	if necesitaArgumentoVi(comando) {
+		if i >= len(teclas) {
+			return viIncompleto
+		}
+		argumento = teclas[i]
+	}
+	destino, _, ok := ed.movimientoVi(comando, cuenta, argumento)
+	if !ok {
+		return viInvalido
+	}
+	ed.pos = destino
+	return viHecho
+}
+
+// necesitaArgumentoVi indica si un movimiento lleva un carácter detrás
+func necesitaArgumentoVi(movimiento rune) bool {
+	return strings.ContainsRune("fFtT", movimiento)
+}
+
+// operarVi aplica un operador al texto entre desde y hasta. Lo borrado o
+// copiado queda en el anillo, de donde lo toman p y P (y también Ctrl-Y).
+func (ed *editorLinea) operarVi(operador rune, desde, hasta int) {
+	if operador == 'y' {
+		if desde < hasta {
+			ed.anillo = append(ed.anillo, append([]rune(nil), ed.buf[desde:hasta]...))
+			if len(ed.anillo) > maximoAnillo {
+				ed.anillo = ed.anillo[1:]
+			}
+		}
+		ed.pos = desde
+		return
+	}
+
+	ed.ultima = accionOtra // Cada borrado ocupa su propio lugar en el anillo
+	if desde < hasta {
+		ed.cortar(desde, hasta, false)
+	} else {
+		ed.guardarDeshacer(accionOtra)
+	}
+	ed.pos = desde
+	if operador == 'c' {
+		ed.modoInsercion(false)
+	}
+}
+
+// movimientoVi calcula a dónde lleva un movimiento de vi repetido n veces.
+//
+// Parámetros:
+//   - movimiento: la tecla del movimiento
+//   - n: cuántas veces repetirlo
+//   - argumento: el carácter que buscan f, F, t y T
+//
+// Retorna:
+//   - int: posición de destino
+//   - bool: true si el movimiento incluye el carácter de destino cuando lo
+//           usa un operador (e, E, $, f y t), como en vi
+//   - bool: false si el movimiento no existe o no encontró su destino
+func (ed *editorLinea) movimientoVi(movimiento rune, n int, argumento rune) (int, bool, bool) {
+	p := ed.pos
+	switch movimiento {
+	case 'h', 0x7f, ctrl('H'):
+		for range n {
+			p = ed.anterior(p)
+		}
+		return p, false, true
+	case 'l', ' ':
+		for range n {
+			p = ed.siguiente(p)
+		}
+		return p, false, true
+	case '0':
+		return 0, false, true
+	case '^':
+		return ed.primerNoBlanco(), false, true
+	case '$':
+		if len(ed.buf) == 0 {
+			return 0, false, true
+		}
+		return ed.anterior(len(ed.buf)), true, true
+	case 'w', 'W':
+		for range n {
+			p = ed.palabraViAdelante(p, movimiento == 'W')
+		}
+		return p, false, true
+	case 'e', 'E':
+		for range n {
+			p = ed.finPalabraVi(p, movimiento == 'E')
+		}
+		return p, true, true
+	case 'b', 'B':
+		for range n {
+			p = ed.palabraViAtras(p, movimiento == 'B')
+		}
+		return p, false, true
+	case 'f', 'F', 't', 'T':
+		ed.viBusqueda = [2]rune{movimiento, argumento}
+		return ed.buscarVi(movimiento, argumento, n)
+	case ';', ',':
+		busqueda := ed.viBusqueda[0]
+		if busqueda == 0 {
+			return 0, false, false
+		}
+		if movimiento == ',' {
+			// La coma busca en la dirección contraria
+			busqueda ^= 'f' ^ 'F'
+		}
+		return ed.buscarVi(busqueda, ed.viBusqueda[1], n)
+	}
+	return 0, false, false
+}
+
+// buscarVi busca la n-ésima aparición de un carácter hacia la derecha (f, t)
+// o hacia la izquierda (F, T) del cursor. t y T se detienen un carácter antes.
+func (ed *editorLinea) buscarVi(busqueda, r rune, n int) (int, bool, bool) {
+	adelante := busqueda == 'f' || busqueda == 't'
+	paso := 1
+	if !adelante {
+		paso = -1
+	}
+	for p := ed.pos + paso; p >= 0 && p < len(ed.buf); p += paso {
+		if ed.buf[p] != r {
+			continue
+		}
+		if n--; n > 0 {
+			continue
+		}
+		switch busqueda {
+		case 't':
+			p--
+		case 'T':
+			p++
+		}
+		return p, adelante, true
+	}
+	return 0, false, false
+}
+
+// primerNoBlanco devuelve la posición del primer carácter que no es espacio
+func (ed *editorLinea) primerNoBlanco() int {
+	p := 0
+	for p < len(ed.buf) && unicode.IsSpace(ed.buf[p]) {
+		p++
+	}
+	return p
+}
+
+// claseVi clasifica un carácter para los movimientos por palabras de vi:
+// espacios (0), letras y dígitos (1) y puntuación (2). Las palabras "grandes"
+// (W, B, E) solo se separan por espacios
+func claseVi(r rune, grande bool) int {
+	switch {
+	case unicode.IsSpace(r):
+		return 0
+	case grande, esPalabra(r), r == '_':
+		return 1
+	default:
+		return 2
+	}
+}
+
+// palabraViAdelante devuelve el comienzo de la palabra siguiente (w, W)
+func (ed *editorLinea) palabraViAdelante(p int, grande bool) int {
+	n := len(ed.buf)
+	if p >= n {
+		return n
+	}
+	clase := claseVi(ed.buf[p], grande)
+	for p < n && clase != 0 && claseVi(ed.buf[p], grande) == clase {
+		p++
+	}
+	for p < n && claseVi(ed.buf[p], grande) == 0 {
+		p++
+	}
+	return p
+}
+
+// finPalabraVi devuelve el último carácter de la palabra actual o, si el
+// cursor ya está en él, el de la siguiente (e, E)
+func (ed *editorLinea) finPalabraVi(p int, grande bool) int {
+	n := len(ed.buf)
+	p++
+	for p < n && claseVi(ed.buf[p], grande) == 0 {
+		p++
+	}
+	if p >= n {
+		return max(0, n-1)
+	}
+	clase := claseVi(ed.buf[p], grande)
+	for p+1 < n && claseVi(ed.buf[p+1], grande) == clase {
+		p++
+	}
+	return p
+}
+
+// palabraViAtras devuelve el comienzo de la palabra actual o, si el cursor
+// ya está en él, el de la anterior (b, B)
+func (ed *editorLinea) palabraViAtras(p int, grande bool) int {
+	if p == 0 {
+		return 0
+	}
+	p--
+	for p > 0 && claseVi(ed.buf[p], grande) == 0 {
+		p--
+	}
+	clase := claseVi(ed.buf[p], grande)
+	for p > 0 && claseVi(ed.buf[p-1], grande) == clase {
+		p--
+	}
+	return p
+}
+
+// repetirCambioVi repite el último cambio (.), con sus teclas de modo
+// inserción. Una cuenta nueva reemplaza a la del cambio original.
+func (ed *editorLinea) repetirCambioVi(cuenta int, hayCuenta bool) int {
+	if len(ed.viCambio) == 0 || ed.viRepitiendo {
+		return viInvalido
+	}
+	teclas := ed.viCambio
+	if hayCuenta {
+		i := 0
+		for i < len(teclas) && teclas[i].r >= '0' && teclas[i].r <= '9' && (i > 0 || teclas[i].r != '0') {
+			i++
+		}
+		var conCuenta []tecla
+		for _, r := range strconv.Itoa(cuenta) {
+			conCuenta = append(conCuenta, tecla{r: r})
+		}
+		teclas = append(conCuenta, teclas[i:]...)
+	}
+
+	ed.viComando = nil
+	ed.viRepitiendo = true
+	for _, t := range teclas {
+		ed.atenderVi(t)
+	}
+	ed.viRepitiendo = false
+	ed.viComando = nil
+	if !ed.viNormal {
+		ed.modoNormal()
+	}
+	return viHecho
+}
+
+// editarExterno abre la línea en un editor externo (tecla v del modo normal):
+// $VISUAL, $EDITOR o vi. Al cerrarlo, el texto guardado se acepta como si se
+// hubiera escrito; si tiene varias líneas, el REPL las ejecuta una por una.
+// Si el editor falla, la edición continúa con la línea original.
+func (ed *editorLinea) editarExterno() (string, bool, error) {
+	sh := ed.sh
+	ed.viComando = nil
+
+	// PASO 1: Escribir la línea en un archivo temporal
+	temporal, err := os.CreateTemp("", "goshell-*.sh")
+	if err != nil {
+		io.WriteString(sh.Stdout, "\a")
+		return "", false, nil
+	}
+	defer os.Remove(temporal.Name())
+	_, err = temporal.WriteString(string(ed.buf) + "\n")
+	if cerrar := temporal.Close(); err == nil {
+		err = cerrar
+	}
+
+	// PASO 2: Ejecutar el editor con la terminal en su modo original
+	editor, _ := sh.variable("VISUAL")
+	if strings.TrimSpace(editor) == "" {
+		editor, _ = sh.variable("EDITOR")
+	}
+	if strings.TrimSpace(editor) == "" {
+		editor = "vi"
+	}
+	campos := strings.Fields(editor)
+
+	ed.pos = len(ed.buf)
+	ed.refrescar()
+	ed.saltarLinea()
+	ed.restaurarTerminal()
+	if err == nil {
+		err = sh.ejecutarEditor(campos, temporal.Name())
+	}
+	if f, ok := sh.Stdin.(*os.File); ok && esTerminal(f) {
+		if restaurar, errCrudo := modoCrudo(f); errCrudo == nil {
+			ed.terminalMu.Lock()
+			ed.restaurar = restaurar
+			ed.terminalMu.Unlock()
+		}
+	}
+
+	// PASO 3: Aceptar el texto guardado, o seguir editando si hubo un error
+	var contenido []byte
+	if err == nil {
+		contenido, err = os.ReadFile(temporal.Name())
+	}
+	if err != nil {
+		fmt.Fprintf(sh.Stdout, "goshell: %s: %v\r\n", campos[0], err)
+		ed.filaCursor = 0
+		ed.refrescar()
+		return "", false, nil
+	}
+	texto := strings.TrimRight(string(contenido), "\n")
+	if texto != "" {
+		io.WriteString(sh.Stdout, strings.ReplaceAll(texto, "\n", "\r\n")+"\r\n")
+	}
+	return texto + "\n", true, nil
+}
+
+// ejecutarEditor ejecuta el editor externo sobre un archivo, con el entorno,
+// el directorio y los flujos estándar de la shell
+func (sh *Shell) ejecutarEditor(campos []string, archivo string) error {
+	ruta, err := sh.buscarPrograma(campos[0])
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(ruta, append(campos[1:], archivo)...)
+	cmd.Args[0] = campos[0]
+	cmd.Env = sh.Env
+	cmd.Dir = sh.Dir
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = sh.Stdin, sh.Stdout, sh.Stderr
+	return cmd.Run()
+}
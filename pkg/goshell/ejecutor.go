@@ -0,0 +1,700 @@
+// Módulo ejecutor: Maneja la ejecución de comandos tanto internos como externos
+// Se encarga de la lógica de ejecución, redirección de E/S y manejo de procesos
+package goshell
+
+import (
+	"context" // Para cancelar los procesos externos
+	"errors"  // Para crear errores simples de los comandos internos
+	"fmt"     // Para formatear salida y mostrar mensajes
+	"os"      // Para operaciones del sistema operativo
+	"os/exec" // Para ejecutar programas externos
+	"path/filepath" // Para resolver rutas desde el directorio de la shell
+	"strconv" // Para interpretar el código de salida de exit
+	"strings" // Para reconstruir la línea de comando de los trabajos
+	"sync"    // Para esperar a los comandos internos de una tubería
+)
+
+// EjecutarComando es la función principal que actúa como dispatcher de comandos.
+// Determina si un comando es interno (built-in) o externo y delega su ejecución.
+//
+// Los comandos internos son los del registro de la shell (ver builtins.go):
+// cd, exit, set, wait, kill, disown, trap, timeout, hash, help, type, command
+// y builtin, además de los que se registren con sh.builtins.Registrar.
+// 
+// Todos los demás comandos se consideran externos y se buscan en el PATH del sistema.
+//
+// Parámetros:
+//   - comando: string con el nombre del comando a ejecutar
+//   - args: slice de strings con los argumentos del comando
+//   - segundoPlano: boolean que indica si debe ejecutarse en background
+//
+// Retorna:
+//   - error: nil si la ejecución fue exitosa, error específico en caso contrario
+func (sh *Shell) EjecutarComando(comando string, args []string, segundoPlano bool) error {
+	// El aviso de exit sobre trabajos en ejecución solo se da una vez seguida:
+	// cualquier otro comando lo vuelve a habilitar, igual que en bash
+	if comando != "exit" {
+		sh.avisoSalidaMostrado = false
+	}
+
+	// Resolver el nombre en el orden de la shell (ver resolverComando), el
+	// mismo que muestran type y command -V. Un comando interno se ejecuta en
+	// el propio proceso con los flujos estándar de la shell
+	if r := sh.resolverComando(comando, false); len(r) > 0 && r[0].tipo == tipoInterno {
+		return r[0].interno.Ejecutar(sh, args, sh.estandar())
+	}
+
+	// Comando externo: delegar a ejecutarComandoExterno
+	return sh.ejecutarComandoExterno(comando, args, segundoPlano)
+}
+
+// EjecutarLinea analiza y ejecuta una línea completa de entrada. Además de
+// ejecutar el comando, ejecuta las trampas DEBUG (antes) y ERR (después, si el
+// comando falló) y actualiza el estado del último comando ($?).
+//
+// Parámetros:
+//   - linea: texto ingresado por el usuario o comando de una trampa
+//
+// Retorna:
+//   - error: el error del comando, o *SalidaShell si la shell debe terminar
+func (sh *Shell) EjecutarLinea(linea string) error {
+	tub, err := AnalizarTuberia(linea)
+	if err != nil {
+		return err
+	}
+
+	// Las líneas vacías o con solo espacios no hacen nada
+	if tub == nil {
+		return nil
+	}
+
+	// Con "set -n" (noexec) solo se comprueba la sintaxis. Como en bash, la
+	// opción se ignora en una shell interactiva, que si no quedaría inservible
+	if sh.opciones[optNoexec].Load() && !sh.interactiva {
+		return nil
+	}
+
+	if err := sh.expandirTuberia(tub); err != nil {
+		sh.ultimoEstado = 1
+		return err
+	}
+
+	if _, err := sh.ejecutarTrampa(trampaDepurar); err != nil {
+		return err
+	}
+
+	// Con "set -x" (xtrace) se muestra cada comando ya expandido
+	if sh.opciones[optXtrace].Load() {
+		sh.mostrarTraza(tub)
+	}
+
+	err = sh.ejecutarTuberia(tub)
+
+	// El comando exit devuelve un error centinela que se propaga tal cual
+	var salida *SalidaShell
+	if errors.As(err, &salida) {
+		return err
+	}
+
+	sh.ultimoEstado = codigoSalida(err)
+	if sh.ultimoEstado != 0 {
+		if _, errTrampa := sh.ejecutarTrampa(trampaError); errTrampa != nil {
+			return errTrampa
+		}
+
+		// Con "set -e" (errexit) la shell termina tras un comando fallido
+		if sh.opciones[optErrexit].Load() && !sh.enTrampa {
+			return &SalidaShell{Estado: sh.ultimoEstado, Causa: err}
+		}
+	}
+	return err
+}
+
+// expandirTuberia sustituye las variables en el nombre, los argumentos y los
+// archivos de redirección de cada comando de la tubería. Con "set -u"
+// (nounset) una variable sin definir es un error.
+func (sh *Shell) expandirTuberia(tub *Tuberia) error {
+	estricto := sh.opciones[optNounset].Load()
+	for i := range tub.Comandos {
+		c := &tub.Comandos[i]
+
+		var err error
+		if c.Nombre, err = sh.expandirPalabra(c.Nombre, estricto); err != nil {
+			return err
+		}
+		for j := range c.Args {
+			if c.Args[j], err = sh.expandirPalabra(c.Args[j], estricto); err != nil {
+				return err
+			}
+		}
+		for j := range c.Redirecciones {
+			if c.Redirecciones[j].Destino, err = sh.expandirPalabra(c.Redirecciones[j].Destino, estricto); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mostrarTraza escribe en stderr cada comando de la tubería precedido por el
+// valor de PS4 ("+ " por defecto), como hace "set -x" en bash.
+func (sh *Shell) mostrarTraza(tub *Tuberia) {
+	ps4, definida := sh.variable("PS4")
+	if !definida {
+		ps4 = "+ "
+	}
+	for _, c := range tub.Comandos {
+		fmt.Fprintln(sh.Stderr, ps4+strings.Join(append([]string{c.Nombre}, c.Args...), " "))
+	}
+}
+
+// rutaAbsoluta resuelve una ruta relativa respecto del directorio de la shell.
+// Todos los comandos internos y las redirecciones deben usarla en lugar de
+// confiar en el directorio del proceso.
+func (sh *Shell) rutaAbsoluta(ruta string) string {
+	if filepath.IsAbs(ruta) || sh.Dir == "" {
+		return filepath.Clean(ruta)
+	}
+	return filepath.Join(sh.Dir, ruta)
+}
+
+// ejecutarExit implementa el comando interno 'exit' para terminar la shell.
+//
+// Comportamiento:
+//   - Sin argumentos: termina con el estado del último comando ($?)
+//   - Con argumento N: termina con el estado N (módulo 256)
+//
+// No llama a os.Exit: devuelve el error centinela *SalidaShell para que el
+// bucle REPL termine y se ejecuten las funciones de limpieza (trap EXIT,
+// historial, terminal, SIGHUP a los trabajos) antes de salir.
+//
+// Si hay trabajos en ejecución, el primer exit solo muestra una advertencia y
+// un segundo exit consecutivo termina la shell.
+//
+// Parámetros:
+//   - sh: shell que debe terminar
+//   - args: slice de argumentos del comando exit
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: *SalidaShell con el estado de salida, o un error si la shell no
+//            debe terminar (advertencia de trabajos, demasiados argumentos)
+func ejecutarExit(sh *Shell, args []string, es EntradaSalida) error {
+	if len(args) > 1 {
+		return errors.New("exit: demasiados argumentos")
+	}
+
+	if sh.trabajos.EnEjecucion() > 0 && !sh.avisoSalidaMostrado {
+		sh.avisoSalidaMostrado = true
+		return errors.New("exit: hay trabajos en ejecución (escribe exit otra vez para salir)")
+	}
+
+	// Sin argumentos se usa el estado del último comando, como en bash
+	estado := sh.ultimoEstado
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			// bash también termina en este caso, con el estado 2
+			fmt.Fprintf(es.Error, "exit: %s: se requiere un argumento numérico\n", args[0])
+			return &SalidaShell{Estado: 2}
+		}
+		estado = n & 0xFF
+	}
+	return &SalidaShell{Estado: estado}
+}
+
+// codigoSalida traduce el resultado de un comando a su código de salida ($?).
+//
+// Parámetros:
+//   - err: error devuelto por EjecutarComando
+//
+// Retorna:
+//   - int: 0 si no hubo error, el código del proceso o del comando interno si
+//          lo hay, 127 si el programa no se encontró, 2 para errores de
+//          sintaxis y 1 para otros errores
+func codigoSalida(err error) int {
+	var estado EstadoSalida
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &estado):
+		return int(estado)
+	case errors.As(err, &exitErr):
+		_, codigo := estadoDeEspera(exitErr)
+		return codigo
+	case errors.Is(err, exec.ErrNotFound):
+		return 127
+	case errors.Is(err, errSintaxis):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// ejecutarComandoExterno maneja la ejecución de un programa externo del sistema.
+// Es el caso más simple de una tubería: un único comando sin redirecciones.
+//
+// Parámetros:
+//   - comando: nombre del programa a ejecutar (ej: "ls", "cat", "grep")
+//   - args: argumentos para pasar al programa
+//   - segundoPlano: true para ejecución asíncrona, false para síncrona
+//
+// Retorna:
+//   - error: nil si la ejecución fue exitosa (o inició, en segundo plano), error en caso contrario
+func (sh *Shell) ejecutarComandoExterno(comando string, args []string, segundoPlano bool) error {
+	tub := &Tuberia{
+		Comandos:     []Comando{{Nombre: comando, Args: args}},
+		SegundoPlano: segundoPlano,
+		Texto:        strings.Join(append([]string{comando}, args...), " "),
+	}
+	return sh.ejecutarEtapas(tub)
+}
+
+// ejecutarTuberia ejecuta una tubería ya analizada y expandida. Un comando
+// simple sin redirecciones pasa por EjecutarComando, que distingue comandos
+// internos y externos; las tuberías y redirecciones se ejecutan por etapas.
+//
+// Parámetros:
+//   - tub: tubería a ejecutar
+//
+// Retorna:
+//   - error: el error del comando (o del último, según pipefail)
+func (sh *Shell) ejecutarTuberia(tub *Tuberia) error {
+	if len(tub.Comandos) == 1 && len(tub.Comandos[0].Redirecciones) == 0 {
+		c := tub.Comandos[0]
+		return sh.EjecutarComando(c.Nombre, c.Args, tub.SegundoPlano)
+	}
+	return sh.ejecutarEtapas(tub)
+}
+
+// ejecutarEtapas ejecuta cada comando de una tubería conectando la salida de
+// cada uno con la entrada del siguiente. Los programas externos se ejecutan
+// como procesos hijos con os/exec; los comandos internos, en una goroutine de
+// la propia shell que lee y escribe en los mismos extremos de tubería.
+//
+// Funcionalidad:
+//   - Busca cada programa en el PATH del entorno de la shell
+//   - Configura redirección de stdin, stdout y stderr (terminal, tuberías y archivos)
+//   - Maneja ejecución síncrona (foreground) y asíncrona (background)
+//   - Para procesos en background, usa goroutines para no bloquear la shell
+//
+// Un comando interno solo, con redirecciones, se ejecuta directamente en el
+// bucle principal: así "exit > /dev/null" o "cd /tmp 2> errores" afectan a
+// la shell igual que sin redirecciones.
+//
+// Parámetros:
+//   - tub: tubería de comandos a ejecutar
+//
+// Retorna:
+//   - error: nil si la ejecución fue exitosa (o inició, en segundo plano),
+//            error en caso contrario (el del último comando, o según pipefail)
+func (sh *Shell) ejecutarEtapas(tub *Tuberia) error {
+	n := len(tub.Comandos)
+	etapas := make([]EntradaSalida, n)
+	internos := make([]Builtin, n)
+
+	// Los archivos y extremos de tubería de los procesos externos los cierra la
+	// shell en cuanto los hijos los heredaron; los de un comando interno, la
+	// goroutine que lo ejecuta, cuando el comando termina
+	var abiertos []*os.File
+	propios := make([][]*os.File, n)
+	asignar := func(i int, f *os.File) {
+		if internos[i] != nil {
+			propios[i] = append(propios[i], f)
+		} else {
+			abiertos = append(abiertos, f)
+		}
+	}
+	defer func() {
+		for _, f := range abiertos {
+			f.Close()
+		}
+	}()
+	cerrarPropios := func() {
+		for _, archivos := range propios {
+			for _, f := range archivos {
+				f.Close()
+			}
+		}
+	}
+
+	// PASO 1: Configurar redirección de E/S
+	// Conectar los streams de cada comando con los de la shell padre
+	// Esto permite que la salida del comando aparezca en la terminal
+	for i, c := range tub.Comandos {
+		internos[i], _ = sh.builtins.Buscar(c.Nombre)
+		if internos[i] == nil && n == 1 && !tub.SegundoPlano {
+			// Con autocd, un directorio escrito como comando es un cd
+			internos[i] = sh.cdAutomatico(c.Nombre)
+		}
+		if internos[i] == nil {
+			// Un comando que no existe se atiende como un comando interno
+			// que informa el error (ver sugerencias.go)
+			internos[i] = sh.comandoNoEncontrado(c.Nombre)
+		}
+		etapas[i] = sh.estandar()
+
+		// Un trabajo en segundo plano sobrevive a la llamada a Run que lo
+		// lanzó: cancelar su contexto no lo detiene
+		if tub.SegundoPlano {
+			etapas[i].Contexto = context.Background()
+		}
+
+		// Un trabajo en segundo plano no debe competir con el REPL por el teclado:
+		// si leyera de la terminal podría robarse lo que el usuario escribe en el prompt.
+		// Con Stdin en nil, os/exec conecta la entrada del proceso a /dev/null.
+		// La opción "set -o bgstdin" permite mantener la terminal como entrada.
+		if tub.SegundoPlano && !sh.opciones[optBgStdin].Load() {
+			etapas[i].Entrada = nil
+		}
+
+		// Tubería: la entrada de este comando es la salida del anterior
+		if i > 0 {
+			lectura, escritura, err := os.Pipe()
+			if err != nil {
+				cerrarPropios()
+				return err
+			}
+			asignar(i-1, escritura)
+			asignar(i, lectura)
+			etapas[i-1].Salida = escritura
+			etapas[i].Entrada = lectura
+		}
+	}
+
+	// Las redirecciones se aplican después de las tuberías, por lo que tienen
+	// prioridad sobre ellas (igual que en bash)
+	for i, c := range tub.Comandos {
+		archivos, err := sh.aplicarRedirecciones(&etapas[i], c.Redirecciones)
+		for _, f := range archivos {
+			asignar(i, f)
+		}
+		if err != nil {
+			cerrarPropios()
+			return err
+		}
+	}
+
+	// Un comando interno solo se ejecuta en el bucle principal
+	if n == 1 && internos[0] != nil {
+		defer cerrarPropios()
+		c := tub.Comandos[0]
+		return sh.ejecutarInterno(internos[0], c.Args, etapas[0])
+	}
+
+	// PASO 2: Iniciar todos los comandos de la tubería
+	// cmd.Start() inicia el proceso pero NO espera a que termine.
+	//
+	// En segundo plano, los procesos se lanzan en un grupo de procesos propio
+	// (el del primer comando), de modo que kill %N llegue a toda la tubería.
+	// Si conservan la terminal como entrada se quedan en el grupo de la shell,
+	// porque un grupo en segundo plano que lee la terminal recibe SIGTTIN.
+	// Lo mismo ocurre en primer plano si el contexto puede cancelarse, para
+	// que la cancelación llegue también a los procesos que creen los comandos.
+	cmds := make([]*procesoExterno, n)
+	errs := make([]error, n)
+	var internosActivos sync.WaitGroup
+	enGrupo := !leeTerminal(etapas)
+	if !tub.SegundoPlano {
+		enGrupo = enGrupo && etapas[0].contexto().Done() != nil
+	}
+	pgid, grupo := 0, false
+	var lider *os.Process // Primer proceso de la tubería
+	for i, c := range tub.Comandos {
+		if internos[i] != nil {
+			// Cada comando interno corre en su propia subshell, creada antes
+			// de lanzar la goroutine para no leer el estado de la shell
+			// mientras otras etapas se ejecutan
+			sub := sh.subshell()
+			internosActivos.Add(1)
+			go func(i int, c Comando) {
+				defer internosActivos.Done()
+				errs[i] = sub.ejecutarInternoEnTuberia(internos[i], c.Args, etapas[i])
+				for _, f := range propios[i] {
+					f.Close()
+				}
+			}(i, c)
+			continue
+		}
+
+		// Si el programa no existe o no puede iniciarse, el resto de la
+		// tubería se ejecuta igualmente, como en bash
+		cmd, propio, err := sh.iniciarProceso(c, etapas[i], enGrupo, lider)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		cmds[i] = cmd
+		if lider == nil {
+			lider = cmd.Process
+			pgid, grupo = cmd.Process.Pid, propio
+		}
+	}
+
+	// esperarTodos espera a cada proceso iniciado y a los comandos internos,
+	// y combina sus resultados
+	esperarTodos := func() error {
+		for i, cmd := range cmds {
+			if cmd != nil {
+				errs[i] = cmd.Wait()
+			}
+		}
+		internosActivos.Wait()
+		return sh.resultadoTuberia(errs)
+	}
+
+	// PASO 3: Determinar modo de ejecución (foreground vs background)
+	// Sin ningún proceso no hay trabajo que registrar: la tubería solo tiene
+	// comandos internos, o ningún programa pudo iniciarse
+	if tub.SegundoPlano && pgid != 0 {
+		// EJECUCIÓN EN SEGUNDO PLANO (ASÍNCRONA)
+		
+		// Registrar la tubería en la tabla de trabajos para poder informar
+		// su terminación más adelante
+		trabajo := sh.trabajos.Agregar(pgid, grupo, tub.Texto)
+		sh.ultimoPIDSegundoPlano = pgid
+
+		// Mostrar información del proceso en background al usuario
+		fmt.Fprintf(sh.Stdout, "[PID: %d] Proceso en segundo plano iniciado (trabajo %%%d)\n", trabajo.PID, trabajo.ID)
+		
+		// Lanzar una goroutine para esperar la terminación de los procesos
+		// Esto evita procesos zombie y libera recursos cuando terminan
+		go func() {
+			// cmd.Wait() espera a que cada proceso termine y libera recursos
+			// Se ejecuta en una goroutine separada para no bloquear la shell principal
+			sh.trabajos.Finalizar(trabajo, esperarTodos())
+
+			// Con "set -b" se avisa en el momento; si no, el bucle REPL
+			// lo notificará justo antes de mostrar el siguiente prompt
+			if sh.opciones[optNotify].Load() {
+				sh.notificarTrabajoInmediato(trabajo)
+			}
+		}()
+		
+		// Retornar inmediatamente para que la shell pueda procesar más comandos
+		return nil
+	}
+
+	// EJECUCIÓN EN PRIMER PLANO (SÍNCRONA)
+	// La shell se bloquea hasta que todos los comandos terminen. Antes de
+	// esperar se cierran las copias de la shell de las tuberías, para que
+	// cada comando reciba fin de archivo cuando el anterior termine
+	for _, f := range abiertos {
+		f.Close()
+	}
+	abiertos = nil
+	return esperarTodos()
+}
+
+// iniciarProceso busca un programa externo en el PATH de la shell y lo inicia
+// con el entorno y el directorio de trabajo de la shell. Si el contexto de los
+// flujos se cancela, el proceso (o su grupo) recibe SIGTERM y, si sigue vivo
+// pasado Shell.EsperaCancelacion, SIGKILL (ver cancelarProceso).
+//
+// Parámetros:
+//   - c: comando a ejecutar, ya expandido
+//   - es: flujos y contexto del proceso
+//   - enGrupo: true para lanzarlo en un grupo de procesos propio, o en el
+//     de lider
+//   - lider: primer proceso de la tubería, que encabeza su grupo, o nil si
+//     este es el primero
+//
+// Retorna:
+//   - *procesoExterno: el proceso ya iniciado
+//   - bool: true si el proceso se ejecuta en un grupo de procesos propio
+//   - error: si el programa no se encontró o no pudo iniciarse
+func (sh *Shell) iniciarProceso(c Comando, es EntradaSalida, enGrupo bool, lider *os.Process) (*procesoExterno, bool, error) {
+	ruta, err := sh.buscarPrograma(c.Nombre)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Crear el comando usando exec.CommandContext, con el entorno y el
+	// directorio de trabajo de la shell
+	cmd := exec.CommandContext(es.contexto(), ruta, c.Args...)
+	cmd.Args[0] = c.Nombre
+	cmd.Env = sh.Env
+	cmd.Dir = sh.Dir
+	cmd.Stdin = es.Entrada // Entrada estándar: teclado o tubería → proceso hijo
+	cmd.Stdout = es.Salida // Salida estándar: proceso hijo → pantalla o tubería
+	cmd.Stderr = es.Error  // Error estándar: proceso hijo → pantalla
+
+	pgid := 0
+	if lider != nil {
+		pgid = lider.Pid
+	}
+	grupo := enGrupo && prepararGrupoProcesos(cmd, pgid)
+	proceso := &procesoExterno{Cmd: cmd}
+	cmd.Cancel = func() error {
+		// Las señales van al grupo de la tubería, que encabeza el primer
+		// proceso, o solo a este proceso si no tiene grupo propio
+		if grupo {
+			proceso.grupo = pgid
+			if proceso.grupo == 0 {
+				proceso.grupo = cmd.Process.Pid
+			}
+		}
+		var err error
+		proceso.detencion, err = sh.cancelarProceso(cmd.Process, proceso.grupo)
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		// Error iniciando el proceso (ej: sin permiso de ejecución)
+		return nil, false, err
+	}
+	path, _ := sh.variable("PATH")
+	sh.hash.Usar(path, c.Nombre)
+	return proceso, grupo, nil
+}
+
+// ejecutarPrograma ejecuta un programa externo con los flujos de un comando
+// interno y espera a que termine. Lo usan los comandos internos que lanzan
+// programas, como command y timeout. Si el programa no existe se informa
+// igual que en una tubería (ver comandoNoEncontrado).
+//
+// Parámetros:
+//   - c: programa a ejecutar y sus argumentos
+//   - es: flujos y contexto del programa
+//
+// Retorna:
+//   - error: el resultado del programa
+func (sh *Shell) ejecutarPrograma(c Comando, es EntradaSalida) error {
+	if noEncontrado := sh.comandoNoEncontrado(c.Nombre); noEncontrado != nil {
+		return noEncontrado.Ejecutar(sh, c.Args, es)
+	}
+
+	// Si el contexto puede cancelarse, el programa va en su propio grupo de
+	// procesos para que la cancelación alcance a sus hijos (ver ejecutarEtapas)
+	enGrupo := !esTerminal(es.Entrada) && es.contexto().Done() != nil
+	cmd, _, err := sh.iniciarProceso(c, es, enGrupo, nil)
+	if err != nil {
+		return err
+	}
+	return cmd.Wait()
+}
+
+// leeTerminal indica si algún comando de la tubería lee de la terminal. Esos
+// procesos deben quedarse en el grupo de la shell: un grupo que no es el de
+// primer plano de la terminal recibe SIGTTIN al leerla.
+func leeTerminal(etapas []EntradaSalida) bool {
+	for _, es := range etapas {
+		if esTerminal(es.Entrada) {
+			return true
+		}
+	}
+	return false
+}
+
+// ejecutarInterno ejecuta un comando interno con flujos propios (tuberías o
+// redirecciones). Los mensajes de error se escriben en el error estándar del
+// comando, de modo que "2> archivo" también los redirige, y se devuelve solo
+// el estado de salida. El error centinela de exit se devuelve tal cual.
+func (sh *Shell) ejecutarInterno(b Builtin, args []string, es EntradaSalida) error {
+	if es.Entrada == nil {
+		// Igual que un proceso en segundo plano, lee de una entrada vacía
+		es.Entrada = strings.NewReader("")
+	}
+
+	err := b.Ejecutar(sh, args, es)
+	var salida *SalidaShell
+	if _, soloEstado := err.(EstadoSalida); err == nil || soloEstado || errors.As(err, &salida) {
+		return err
+	}
+	fmt.Fprintf(es.Error, "goshell: %v\n", err)
+	return EstadoSalida(codigoSalida(err))
+}
+
+// ejecutarInternoEnTuberia ejecuta un comando interno como una etapa de una
+// tubería, en la subshell sh (ver subshell). Igual que en bash, donde cada
+// etapa corre en una subshell, exit dentro de una tubería no termina la
+// shell: solo fija el estado de la etapa.
+func (sh *Shell) ejecutarInternoEnTuberia(b Builtin, args []string, es EntradaSalida) error {
+	// Una trampa definida en la etapa no debe dejar la subshell suscrita
+	defer sh.dejarSenales()
+
+	err := sh.ejecutarInterno(b, args, es)
+	var salida *SalidaShell
+	if errors.As(err, &salida) {
+		return resultadoEstado(salida.Estado)
+	}
+	return err
+}
+
+// resultadoTuberia elige el error que representa a toda la tubería: el del
+// último comando o, con "set -o pipefail", el del último comando que falló.
+func (sh *Shell) resultadoTuberia(errs []error) error {
+	if sh.opciones[optPipefail].Load() {
+		for i := len(errs) - 1; i >= 0; i-- {
+			if errs[i] != nil {
+				return errs[i]
+			}
+		}
+		return nil
+	}
+	return errs[len(errs)-1]
+}
+
+// aplicarRedirecciones abre los archivos de las redirecciones de un comando y
+// los conecta a su entrada, salida o error estándar.
+//
+// Parámetros:
+//   - es: flujos del comando a configurar
+//   - redirecciones: redirecciones en el orden en que se escribieron
+//
+// Retorna:
+//   - []*os.File: archivos abiertos, que deben cerrarse cuando el comando los
+//     haya heredado (proceso externo) o haya terminado (comando interno)
+//   - error: si algún archivo no pudo abrirse (o noclobber impide sobrescribirlo)
+func (sh *Shell) aplicarRedirecciones(es *EntradaSalida, redirecciones []Redireccion) ([]*os.File, error) {
+	var abiertos []*os.File
+	for _, red := range redirecciones {
+		// Duplicar un descriptor: 2>&1 y 1>&2
+		if red.Operador == ">&" {
+			if red.Descriptor == 2 {
+				es.Error = es.Salida
+			} else {
+				es.Salida = es.Error
+			}
+			continue
+		}
+
+		archivo, err := sh.abrirRedireccion(red)
+		if err != nil {
+			return abiertos, err
+		}
+		abiertos = append(abiertos, archivo)
+
+		switch red.Descriptor {
+		case 0:
+			es.Entrada = archivo
+		case 1:
+			es.Salida = archivo
+		case 2:
+			es.Error = archivo
+		}
+	}
+	return abiertos, nil
+}
+
+// abrirRedireccion abre el archivo de una redirección con el modo adecuado.
+// Con "set -o noclobber", ">" no sobrescribe archivos regulares existentes;
+// ">|" permite hacerlo de todas formas.
+func (sh *Shell) abrirRedireccion(red Redireccion) (*os.File, error) {
+	// Los archivos relativos se abren desde el directorio de la shell
+	ruta := sh.rutaAbsoluta(red.Destino)
+	switch red.Operador {
+	case "<":
+		return os.Open(ruta)
+	case ">>":
+		return os.OpenFile(ruta, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	case ">":
+		if sh.opciones[optNoclobber].Load() {
+			if info, err := os.Stat(ruta); err == nil && info.Mode().IsRegular() {
+				return nil, fmt.Errorf("%s: no se puede sobrescribir un archivo existente", red.Destino)
+			}
+		}
+	}
+	return os.OpenFile(ruta, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o666)
+}
\ No newline at end of file
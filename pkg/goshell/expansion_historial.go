@@ -0,0 +1,389 @@
+// Módulo expansion_historial: Expansión del historial al estilo de csh, que
+// el REPL aplica a cada línea antes de analizarla: !! repite el comando
+// anterior, !$ su último argumento, ^viejo^nuevo lo corrige, etc. La línea
+// expandida se muestra antes de ejecutarla
+package goshell
+
+import (
+	"errors"        // Para los errores de la expansión
+	"fmt"           // Para mostrar la línea expandida y los errores
+	"io"            // Para escribir la línea expandida
+	"path/filepath" // Para los modificadores :h :t :r :e
+	"strconv"       // Para los números de evento y de palabra
+	"strings"       // Para buscar los eventos y armar la línea
+	"unicode"       // Para reconocer el final de un evento
+)
+
+// expandirHistorial reemplaza las referencias al historial de una línea. Los
+// eventos se buscan en el historial anterior a la línea.
+//
+// Formas soportadas:
+//   - Eventos: !! (el anterior), !n (el número n), !-n (el n-ésimo anterior),
+//     !texto (el último que empieza con texto), !?texto[?] (el último que
+//     contiene texto) y ^viejo^nuevo[^] al comienzo de la línea (el anterior
+//     con viejo reemplazado por nuevo)
+//   - Palabras, tras el evento y ':' (los últimos tres sin ':' también):
+//     :n, :x-y, :x-, :-y, :x*, :^ (la primera), :$ (la última) y :*
+//     (todos los argumentos)
+//   - Modificadores, cada uno tras ':': h (quitar el último componente de la
+//     ruta), t (dejar solo el último), r (quitar la extensión), e (dejar solo
+//     la extensión), s/viejo/nuevo/ (en nuevo, & es viejo), gs/viejo/nuevo/
+//     (todas las apariciones), & y g& (repetir la última sustitución) y p
+//     (mostrar la línea sin ejecutarla)
+//
+// Un '!' seguido de un espacio, '=', '(' o el final de la línea no es una
+// expansión, "\!" es un '!' literal y, como en bash, el texto entre comillas
+// simples se copia sin cambios (ej: echo 'hola!mundo').
+//
+// Parámetros:
+//   - linea: línea leída, con o sin el '\n' final
+//
+// Retorna:
+//   - string: la línea expandida
+//   - bool: true si había alguna expansión (la línea debe mostrarse)
+//   - bool: true si algún evento lleva el modificador :p
+//   - error: si un evento, una palabra o un modificador no son válidos
+func (sh *Shell) expandirHistorial(linea string) (string, bool, bool, error) {
+	r := []rune(linea)
+	var s strings.Builder
+	expandida, mostrar := false, false
+
+	// ^viejo^nuevo^ equivale a !!:s/viejo/nuevo/
+	i := 0
+	if len(r) > 0 && r[0] == '^' {
+		anterior, err := sh.eventoHistorial("!")
+		if err != nil {
+			return "", false, false, err
+		}
+		viejo, nuevo, fin := leerSustitucion(r, 0)
+		texto, err := sh.sustituirHistorial(anterior, viejo, nuevo, false)
+		if err != nil {
+			return "", false, false, err
+		}
+		s.WriteString(texto)
+		i, expandida = fin, true
+	}
+
+	// Dentro de comillas simples no se expande nada; dentro de comillas
+	// dobles sí, y un ' es un carácter más (ej: "it's !!")
+	simples, dobles := false, false
+	for i < len(r) {
+		c := r[i]
+		switch {
+		case c == '\'' && !dobles:
+			simples = !simples
+		case simples:
+		case c == '"':
+			dobles = !dobles
+		case c == '\\' && i+1 < len(r) && r[i+1] == '!':
+			s.WriteRune('!')
+			i += 2
+			continue
+		case c == '\\' && i+1 < len(r):
+			// Un carácter escapado (ej: \') se copia sin interpretarlo
+			s.WriteString(string(r[i : i+2]))
+			i += 2
+			continue
+		}
+		if simples || c != '!' || i+1 >= len(r) || unicode.IsSpace(r[i+1]) || r[i+1] == '=' || r[i+1] == '(' {
+			s.WriteRune(c)
+			i++
+			continue
+		}
+
+		texto, fin, p, err := sh.expandirEvento(r, i)
+		if err != nil {
+			return "", false, false, err
+		}
+		s.WriteString(texto)
+		i, expandida, mostrar = fin, true, mostrar || p
+	}
+	return s.String(), expandida, mostrar, nil
+}
+
+// expandirEvento expande una referencia al historial completa (evento,
+// palabras y modificadores) que empieza en el '!' de la posición inicio.
+//
+// Retorna:
+//   - string: el texto que reemplaza a la referencia
+//   - int: posición siguiente a la referencia
+//   - bool: true si lleva el modificador :p
+//   - error: si la referencia no es válida
+func (sh *Shell) expandirEvento(r []rune, inicio int) (string, int, bool, error) {
+	// PASO 1: Separar el evento
+	i := inicio + 1
+	var evento string
+	switch {
+	case r[i] == '!':
+		evento, i = "!", i+1
+	case r[i] == '?':
+		fin := i + 1
+		for fin < len(r) && r[fin] != '?' && r[fin] != '\n' {
+			fin++
+		}
+		evento, i = string(r[i:fin]), fin
+		if i < len(r) && r[i] == '?' {
+			i++
+		}
+	case strings.ContainsRune(":^$*", r[i]):
+		evento = "!" // Las palabras sin evento son del comando anterior
+	default:
+		fin := i
+		for fin < len(r) && !unicode.IsSpace(r[fin]) && !strings.ContainsRune(":^$*", r[fin]) {
+			fin++
+		}
+		evento, i = string(r[i:fin]), fin
+	}
+	// Los errores muestran la referencia hasta el siguiente espacio, como bash
+	referencia := func() string {
+		fin := inicio
+		for fin < len(r) && !unicode.IsSpace(r[fin]) {
+			fin++
+		}
+		return string(r[inicio:fin])
+	}
+
+	texto, err := sh.eventoHistorial(evento)
+	if err != nil {
+		return "", 0, false, fmt.Errorf("%s: %w", referencia(), err)
+	}
+
+	// PASO 2: Elegir las palabras
+	if i < len(r) && (strings.ContainsRune("^$*", r[i]) ||
+		(r[i] == ':' && i+1 < len(r) && strings.ContainsRune("0123456789^$*-", r[i+1]))) {
+		if r[i] == ':' {
+			i++
+		}
+		elegidas, fin, err := elegirPalabras(texto, r, i)
+		if err != nil {
+			return "", 0, false, fmt.Errorf("%s: %w", referencia(), err)
+		}
+		texto, i = elegidas, fin
+	}
+
+	// PASO 3: Aplicar los modificadores
+	mostrar := false
+	for i+1 < len(r) && r[i] == ':' {
+		global := r[i+1] == 'g' || r[i+1] == 'a'
+		if global {
+			i++
+		}
+		if i+1 >= len(r) {
+			break
+		}
+		switch m := r[i+1]; m {
+		case 'h':
+			texto = filepath.Dir(texto)
+			i += 2
+		case 't':
+			texto = filepath.Base(texto)
+			i += 2
+		case 'r':
+			texto = strings.TrimSuffix(texto, filepath.Ext(texto))
+			i += 2
+		case 'e':
+			texto = filepath.Ext(texto)
+			i += 2
+		case 'p':
+			mostrar = true
+			i += 2
+		case 's', '&':
+			viejo, nuevo := sh.sustitucion[0], sh.sustitucion[1]
+			if m == 's' && i+2 < len(r) {
+				viejo, nuevo, i = leerSustitucion(r, i+2)
+			} else {
+				i += 2
+			}
+			texto, err = sh.sustituirHistorial(texto, viejo, nuevo, global)
+			if err != nil {
+				return "", 0, false, fmt.Errorf("%s: %w", referencia(), err)
+			}
+		default:
+			return "", 0, false, fmt.Errorf("%s: modificador desconocido", referencia())
+		}
+	}
+	return texto, i, mostrar, nil
+}
+
+// eventoHistorial busca un evento del historial anterior a la línea en curso.
+//
+// Parámetros:
+//   - evento: "!" (el anterior), "n", "-n", "?texto" o "texto"
+//
+// Retorna:
+//   - string: el texto del evento
+//   - error: si no hay un evento que coincida
+func (sh *Shell) eventoHistorial(evento string) (string, error) {
+	entradas, primera := sh.historial.copia()
+	errNoEncontrado := errors.New("evento no encontrado")
+
+	indice := -1
+	switch numero, err := strconv.Atoi(evento); {
+	case evento == "!":
+		indice = len(entradas) - 1
+	case err == nil && numero < 0:
+		indice = len(entradas) + numero
+	case err == nil:
+		indice = numero - primera
+	default:
+		buscado, contiene := strings.CutPrefix(evento, "?")
+		for i := len(entradas) - 1; i >= 0 && indice < 0; i-- {
+			if (contiene && strings.Contains(entradas[i].texto, buscado)) ||
+				(!contiene && strings.HasPrefix(entradas[i].texto, buscado)) {
+				indice = i
+			}
+		}
+	}
+	if indice < 0 || indice >= len(entradas) {
+		return "", errNoEncontrado
+	}
+	return entradas[indice].texto, nil
+}
+
+// elegirPalabras interpreta un designador de palabras que empieza en la
+// posición i y devuelve esas palabras del evento, separadas por espacios. Las
+// palabras se cuentan desde 0, que es el nombre del comando.
+//
+// Retorna:
+//   - string: las palabras elegidas
+//   - int: posición siguiente al designador
+//   - error: si el designador pide palabras que el evento no tiene
+func elegirPalabras(evento string, r []rune, i int) (string, int, error) {
+	palabras := strings.Fields(evento)
+	ultima := len(palabras) - 1
+	errPalabra := errors.New("designador de palabra inválido")
+
+	// numero lee un número, ^ o $; -1 si no hay ninguno
+	numero := func() int {
+		switch {
+		case i < len(r) && r[i] == '^':
+			i++
+			return 1
+		case i < len(r) && r[i] == '$':
+			i++
+			return ultima
+		}
+		inicio := i
+		for i < len(r) && r[i] >= '0' && r[i] <= '9' {
+			i++
+		}
+		if inicio == i {
+			return -1
+		}
+		n, _ := strconv.Atoi(string(r[inicio:i]))
+		return n
+	}
+
+	var desde, hasta int
+	switch {
+	case r[i] == '*':
+		// Todos los argumentos; ninguno si el comando no tiene
+		i++
+		if ultima < 1 {
+			return "", i, nil
+		}
+		desde, hasta = 1, ultima
+	case r[i] == '-':
+		i++
+		desde, hasta = 0, numero()
+		if hasta < 0 {
+			hasta = ultima - 1
+		}
+	default:
+		desde = numero()
+		hasta = desde
+		if i < len(r) && r[i] == '*' {
+			i++
+			hasta = ultima
+		} else if i < len(r) && r[i] == '-' {
+			i++
+			if hasta = numero(); hasta < 0 {
+				hasta = ultima - 1 // x- es x* sin la última palabra
+			}
+		}
+	}
+	if desde < 0 || hasta > ultima || desde > hasta {
+		return "", 0, errPalabra
+	}
+	return strings.Join(palabras[desde:hasta+1], " "), i, nil
+}
+
+// leerSustitucion lee un "/viejo/nuevo/" que empieza en la posición i (en el
+// separador, que puede ser cualquier carácter). El separador final puede
+// faltar al terminar la línea, y una barra invertida lo escapa.
+//
+// Retorna:
+//   - string, string: el texto viejo y el nuevo
+//   - int: posición siguiente a la sustitución
+func leerSustitucion(r []rune, i int) (string, string, int) {
+	separador := r[i]
+	i++
+	leer := func() string {
+		var s strings.Builder
+		for i < len(r) && r[i] != separador && r[i] != '\n' {
+			if r[i] == '\\' && i+1 < len(r) && r[i+1] == separador {
+				i++
+			}
+			s.WriteRune(r[i])
+			i++
+		}
+		if i < len(r) && r[i] == separador {
+			i++
+		}
+		return s.String()
+	}
+	viejo := leer()
+	return viejo, leer(), i
+}
+
+// sustituirHistorial reemplaza viejo por nuevo en el texto de un evento (la
+// primera aparición, o todas si global es true) y la recuerda para :& y para
+// un viejo vacío. En nuevo, '&' representa a viejo.
+func (sh *Shell) sustituirHistorial(texto, viejo, nuevo string, global bool) (string, error) {
+	if viejo == "" {
+		viejo = sh.sustitucion[0]
+	}
+	if viejo == "" || !strings.Contains(texto, viejo) {
+		return "", errors.New("la sustitución falló")
+	}
+	sh.sustitucion = [2]string{viejo, nuevo}
+
+	reemplazo := strings.ReplaceAll(nuevo, "&", viejo)
+	if global {
+		return strings.ReplaceAll(texto, viejo, reemplazo), nil
+	}
+	return strings.Replace(texto, viejo, reemplazo, 1), nil
+}
+
+// lineaDelHistorial prepara una línea leída por el REPL para ejecutarla:
+// aplica la expansión del historial y guarda el resultado en el historial.
+// Una línea expandida se muestra antes de ejecutarla o, con histverify,
+// vuelve al editor para que el usuario la revise. Como en bash, solo la shell
+// interactiva usa el historial: con la entrada redirigida (ej: printf ... |
+// goshell) las líneas se ejecutan tal cual y un '!' no tiene nada especial.
+//
+// Retorna:
+//   - string: la línea a ejecutar
+//   - bool: false si no hay que ejecutarla (por un error de la expansión,
+//     por :p o por histverify)
+func (sh *Shell) lineaDelHistorial(linea string) (string, bool) {
+	if !sh.interactiva {
+		return linea, true
+	}
+
+	expandida, cambio, mostrar, err := sh.expandirHistorial(linea)
+	if err != nil {
+		fmt.Fprintln(sh.Stderr, "goshell:", err)
+		return "", false
+	}
+	if cambio && !mostrar && sh.editor != nil && sh.opciones[optHistverify].Load() {
+		sh.editor.pendiente = strings.TrimRight(expandida, "\n")
+		return "", false
+	}
+
+	sh.agregarHistorial(expandida)
+	if cambio {
+		io.WriteString(sh.Stdout, strings.TrimRight(expandida, "\n")+"\n")
+	}
+	return expandida, !mostrar
+}
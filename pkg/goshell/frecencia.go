@@ -0,0 +1,325 @@
+// Módulo frecencia: Registra los directorios que visita la shell interactiva
+// y permite volver a ellos con el comando interno z, eligiendo entre los que
+// coinciden con un patrón el de mayor "frecencia" (frecuencia y reciente)
+package goshell
+
+import (
+	"bufio"         // Para leer la base de datos línea por línea
+	"errors"        // Para los errores de z
+	"fmt"           // Para listar los candidatos
+	"os"            // Para leer y escribir la base de datos
+	"path/filepath" // Para construir la ruta de la base de datos
+	"sort"          // Para ordenar los candidatos por puntuación
+	"strconv"       // Para interpretar los campos de la base de datos
+	"strings"       // Para buscar los patrones en las rutas
+	"time"          // Para la antigüedad de cada visita
+)
+
+// archivoFrecencia es el nombre de la base de datos de directorios, dentro
+// del directorio de datos de la shell
+const archivoFrecencia = "z"
+
+// rangoMaximo es la suma de rangos a partir de la cual se envejece la base
+// de datos: todos los rangos se reducen y se olvidan los que quedan por
+// debajo de 1, igual que en z.sh
+const rangoMaximo = 9000
+
+// entradaFrecencia es un directorio de la base de datos
+type entradaFrecencia struct {
+	ruta   string    // Ruta absoluta del directorio
+	rango  float64   // Crece en 1 con cada visita
+	ultimo time.Time // Momento de la última visita
+}
+
+// puntuacion combina el rango de un directorio con la antigüedad de su última
+// visita: las visitas de la última hora valen el cuádruple y las de hace más
+// de una semana, la cuarta parte.
+func (e entradaFrecencia) puntuacion(ahora time.Time) float64 {
+	antiguedad := ahora.Sub(e.ultimo)
+	switch {
+	case antiguedad < time.Hour:
+		return e.rango * 4
+	case antiguedad < 24*time.Hour:
+		return e.rango * 2
+	case antiguedad < 7*24*time.Hour:
+		return e.rango / 2
+	default:
+		return e.rango / 4
+	}
+}
+
+// directorioDatos devuelve el directorio en el que la shell guarda sus datos:
+// $XDG_DATA_HOME/goshell, o ~/.local/share/goshell si la variable no está
+// definida.
+//
+// Retorna:
+//   - string: ruta del directorio (puede no existir todavía)
+//   - bool: false si no hay ni $XDG_DATA_HOME ni $HOME
+func (sh *Shell) directorioDatos() (string, bool) {
+	if dir, ok := sh.variable("XDG_DATA_HOME"); ok && filepath.IsAbs(dir) {
+		return filepath.Join(dir, "goshell"), true
+	}
+	if home, ok := sh.variable("HOME"); ok && home != "" {
+		return filepath.Join(home, ".local", "share", "goshell"), true
+	}
+	return "", false
+}
+
+// leerFrecencia lee la base de datos de directorios. Cada línea tiene el
+// formato de z.sh: "ruta|rango|segundos desde 1970". Las líneas inválidas se
+// ignoran y un archivo inexistente es una base de datos vacía.
+func leerFrecencia(ruta string) ([]entradaFrecencia, error) {
+	f, err := os.Open(ruta)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entradas []entradaFrecencia
+	lector := bufio.NewScanner(f)
+	for lector.Scan() {
+		campos := strings.Split(lector.Text(), "|")
+		if len(campos) != 3 || !filepath.IsAbs(campos[0]) {
+			continue
+		}
+		rango, err1 := strconv.ParseFloat(campos[1], 64)
+		segundos, err2 := strconv.ParseInt(campos[2], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		entradas = append(entradas, entradaFrecencia{campos[0], rango, time.Unix(segundos, 0)})
+	}
+	return entradas, lector.Err()
+}
+
+// escribirFrecencia guarda la base de datos de directorios. Se escribe en un
+// archivo temporal que luego reemplaza al original, para que otra shell que
+// la lea al mismo tiempo nunca vea un archivo a medias.
+func escribirFrecencia(ruta string, entradas []entradaFrecencia) error {
+	if err := os.MkdirAll(filepath.Dir(ruta), 0o700); err != nil {
+		return err
+	}
+
+	var contenido strings.Builder
+	for _, e := range entradas {
+		fmt.Fprintf(&contenido, "%s|%s|%d\n", e.ruta, strconv.FormatFloat(e.rango, 'f', -1, 64), e.ultimo.Unix())
+	}
+
+	temporal, err := os.CreateTemp(filepath.Dir(ruta), archivoFrecencia+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporal.Name()) // No hace nada si el rename funcionó
+	if _, err := temporal.WriteString(contenido.String()); err != nil {
+		temporal.Close()
+		return err
+	}
+	if err := temporal.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temporal.Name(), ruta)
+}
+
+// registrarVisita suma una visita a un directorio en la base de datos. Solo
+// registra las visitas de la shell interactiva (como z.sh, que lo hace desde
+// el prompt): los scripts y las shells integradas en otros programas no
+// modifican la base de datos. El directorio $HOME no se registra.
+//
+// Parámetros:
+//   - dir: directorio al que se acaba de cambiar
+func (sh *Shell) registrarVisita(dir string) {
+	if !sh.interactiva {
+		return
+	}
+	if home, _ := sh.variable("HOME"); dir == home {
+		return
+	}
+	datos, ok := sh.directorioDatos()
+	if !ok {
+		return
+	}
+	ruta := filepath.Join(datos, archivoFrecencia)
+
+	entradas, err := leerFrecencia(ruta)
+	if err != nil {
+		return
+	}
+
+	// PASO 1: Sumar la visita, agregando el directorio si es nuevo
+	ahora := time.Now()
+	total, encontrado := 0.0, false
+	for i := range entradas {
+		if entradas[i].ruta == dir {
+			entradas[i].rango++
+			entradas[i].ultimo = ahora
+			encontrado = true
+		}
+		total += entradas[i].rango
+	}
+	if !encontrado {
+		entradas = append(entradas, entradaFrecencia{dir, 1, ahora})
+		total++
+	}
+
+	// PASO 2: Envejecer la base de datos cuando crece demasiado
+	if total > rangoMaximo {
+		vigentes := entradas[:0]
+		for _, e := range entradas {
+			e.rango *= 0.99
+			if e.rango >= 1 {
+				vigentes = append(vigentes, e)
+			}
+		}
+		entradas = vigentes
+	}
+
+	if err := escribirFrecencia(ruta, entradas); err != nil {
+		fmt.Fprintln(sh.Stderr, "goshell: no se pudo actualizar la base de datos de directorios:", err)
+	}
+}
+
+// candidatosFrecencia devuelve los directorios existentes de la base de datos
+// que coinciden con los patrones, ordenados de menor a mayor puntuación. Un
+// directorio coincide si contiene todos los patrones en orden, sin distinguir
+// mayúsculas de minúsculas, y el último patrón aparece en su último componente.
+func (sh *Shell) candidatosFrecencia(patrones []string) ([]entradaFrecencia, error) {
+	datos, ok := sh.directorioDatos()
+	if !ok {
+		return nil, nil
+	}
+	entradas, err := leerFrecencia(filepath.Join(datos, archivoFrecencia))
+	if err != nil {
+		return nil, err
+	}
+
+	var candidatos []entradaFrecencia
+	for _, e := range entradas {
+		if !coincidePatrones(e.ruta, patrones) {
+			continue
+		}
+		if info, err := os.Stat(e.ruta); err != nil || !info.IsDir() {
+			continue
+		}
+		candidatos = append(candidatos, e)
+	}
+
+	ahora := time.Now()
+	sort.SliceStable(candidatos, func(i, j int) bool {
+		return candidatos[i].puntuacion(ahora) < candidatos[j].puntuacion(ahora)
+	})
+	return candidatos, nil
+}
+
+// coincidePatrones indica si una ruta contiene todos los patrones en orden.
+// El último patrón debe estar en el último componente de la ruta, para que
+// "z src" prefiera ~/proyecto/src antes que ~/proyecto/src/vendor/lib.
+func coincidePatrones(ruta string, patrones []string) bool {
+	resto := strings.ToLower(ruta)
+	for i, patron := range patrones {
+		patron = strings.ToLower(patron)
+		if i == len(patrones)-1 && !strings.Contains(strings.ToLower(filepath.Base(ruta)), patron) {
+			return false
+		}
+		posicion := strings.Index(resto, patron)
+		if posicion < 0 {
+			return false
+		}
+		resto = resto[posicion+len(patron):]
+	}
+	return true
+}
+
+// ejecutarZ implementa el comando interno 'z': cambia al directorio de mayor
+// frecencia entre los que coinciden con los patrones.
+//
+// Formas soportadas:
+//   - z patrón...: cambia al mejor directorio que coincide con los patrones
+//   - z -l [patrón...] (o z sin argumentos): lista los candidatos con su
+//     puntuación, el mejor al final
+//   - z -x [directorio]: quita de la base de datos un directorio (por defecto,
+//     el actual)
+//
+// Parámetros:
+//   - sh: shell cuyo directorio se cambia
+//   - args: opciones y patrones
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: si ningún directorio coincide o la base de datos no puede leerse
+func ejecutarZ(sh *Shell, args []string, es EntradaSalida) error {
+	listar := len(args) == 0
+	if len(args) > 0 {
+		switch args[0] {
+		case "-l":
+			listar = true
+			args = args[1:]
+		case "-x":
+			return sh.olvidarDirectorio(args[1:])
+		case "--":
+			args = args[1:]
+		default:
+			if strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+				return fmt.Errorf("z: %s: opción inválida\nz: uso: z [-l | -x] [patrón ...]", args[0])
+			}
+		}
+	}
+
+	candidatos, err := sh.candidatosFrecencia(args)
+	if err != nil {
+		return fmt.Errorf("z: %v", err)
+	}
+	if listar {
+		ahora := time.Now()
+		for _, c := range candidatos {
+			fmt.Fprintf(es.Salida, "%-10.1f %s\n", c.puntuacion(ahora), c.ruta)
+		}
+		return nil
+	}
+	if len(candidatos) == 0 {
+		return fmt.Errorf("z: no hay directorios que coincidan con '%s'", strings.Join(args, " "))
+	}
+
+	if err := sh.cambiarDirectorio(candidatos[len(candidatos)-1].ruta, false); err != nil {
+		return fmt.Errorf("z: %w", err)
+	}
+	return nil
+}
+
+// olvidarDirectorio implementa "z -x": quita un directorio de la base de
+// datos. Sin argumentos quita el directorio actual.
+func (sh *Shell) olvidarDirectorio(args []string) error {
+	if len(args) > 1 {
+		return errors.New("z: -x: demasiados argumentos")
+	}
+	dir := sh.Dir
+	if len(args) == 1 {
+		dir = sh.rutaAbsoluta(args[0])
+	}
+
+	datos, ok := sh.directorioDatos()
+	if !ok {
+		return nil
+	}
+	ruta := filepath.Join(datos, archivoFrecencia)
+	entradas, err := leerFrecencia(ruta)
+	if err != nil {
+		return fmt.Errorf("z: %v", err)
+	}
+
+	vigentes := entradas[:0]
+	for _, e := range entradas {
+		if e.ruta != dir {
+			vigentes = append(vigentes, e)
+		}
+	}
+	if len(vigentes) == len(entradas) {
+		return fmt.Errorf("z: %s: no está en la base de datos", dir)
+	}
+	if err := escribirFrecencia(ruta, vigentes); err != nil {
+		return fmt.Errorf("z: %v", err)
+	}
+	return nil
+}
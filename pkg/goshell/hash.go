@@ -0,0 +1,399 @@
+// Módulo hash: Recuerda dónde está cada programa del PATH para no recorrerlo
+// en cada comando, mantiene un índice de todos los programas del PATH (que
+// usan las sugerencias y el completado) y contiene el comando interno hash
+package goshell
+
+import (
+	"errors"        // Para combinar los errores de hash
+	"fmt"           // Para listar la tabla
+	"io"            // Para escribir el listado en la salida del comando
+	"os"            // Para leer los directorios del PATH
+	"os/exec"       // Para comprobar que las rutas recordadas siguen siendo válidas
+	"path/filepath" // Para recorrer los directorios del PATH
+	"sort"          // Para listar la tabla y el índice en orden alfabético
+	"strings"       // Para reconocer rutas en los nombres
+	"sync"          // Para proteger la tabla del acceso concurrente
+)
+
+// rutaHash es un programa recordado en la tabla hash
+type rutaHash struct {
+	ruta string // Ruta absoluta del ejecutable
+	usos int    // Veces que se ejecutó desde que se recordó
+}
+
+// TablaHash recuerda la ruta de los programas del PATH ya encontrados y el
+// índice de todos los programas del PATH. Ambos son válidos para un valor
+// del PATH: cuando el PATH cambia se descartan. Se consulta desde las
+// goroutines de las tuberías y la del índice, por eso se protege con un mutex.
+type TablaHash struct {
+	mu     sync.Mutex
+	path   string               // PATH para el que son válidos rutas e índice
+	rutas  map[string]*rutaHash // Programas recordados, por nombre
+	indice []string             // Nombres de los programas del PATH, ordenados
+	listo  chan struct{}        // Se cierra al terminar de construir el índice; nil si no se empezó
+}
+
+// sincronizar descarta las rutas y el índice si el PATH cambió. Debe llamarse
+// con el mutex tomado.
+func (th *TablaHash) sincronizar(path string) {
+	if th.rutas != nil && th.path == path {
+		return
+	}
+	th.path = path
+	th.rutas = map[string]*rutaHash{}
+	th.indice = nil
+	th.listo = nil
+}
+
+// Buscar devuelve la ruta recordada de un programa.
+//
+// Parámetros:
+//   - path: valor actual del PATH de la shell
+//   - nombre: nombre del programa
+//
+// Retorna:
+//   - string: ruta recordada
+//   - bool: true si el programa estaba en la tabla
+func (th *TablaHash) Buscar(path, nombre string) (string, bool) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	th.sincronizar(path)
+	r, ok := th.rutas[nombre]
+	if !ok {
+		return "", false
+	}
+	return r.ruta, true
+}
+
+// Recordar guarda la ruta de un programa, con el contador de usos a cero
+func (th *TablaHash) Recordar(path, nombre, ruta string) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	th.sincronizar(path)
+	th.rutas[nombre] = &rutaHash{ruta: ruta}
+}
+
+// Olvidar quita un programa de la tabla.
+//
+// Retorna:
+//   - bool: true si el programa estaba en la tabla
+func (th *TablaHash) Olvidar(path, nombre string) bool {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	th.sincronizar(path)
+	_, ok := th.rutas[nombre]
+	delete(th.rutas, nombre)
+	return ok
+}
+
+// Usar cuenta una ejecución de un programa recordado
+func (th *TablaHash) Usar(path, nombre string) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	th.sincronizar(path)
+	if r, ok := th.rutas[nombre]; ok {
+		r.usos++
+	}
+}
+
+// clonar devuelve una tabla independiente con las mismas rutas, para una
+// subshell. El índice ya construido se comparte porque nunca se modifica; si
+// todavía se está construyendo, la copia lo construye por su cuenta cuando
+// lo necesite.
+func (th *TablaHash) clonar() *TablaHash {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	copia := &TablaHash{path: th.path, indice: th.indice}
+	if th.rutas != nil {
+		copia.rutas = make(map[string]*rutaHash, len(th.rutas))
+		for nombre, r := range th.rutas {
+			copia.rutas[nombre] = &rutaHash{ruta: r.ruta, usos: r.usos}
+		}
+	}
+	if th.indice != nil {
+		copia.listo = th.listo
+	}
+	return copia
+}
+
+// Vaciar olvida todas las rutas y el índice (hash -r), por ejemplo después de
+// instalar programas nuevos en el PATH
+func (th *TablaHash) Vaciar() {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	th.rutas = nil
+}
+
+// buscarPrograma busca un programa en los directorios del PATH del entorno de
+// la shell (Shell.Env), no en el del proceso. Un nombre que contiene un
+// separador de directorio se usa tal cual. Las rutas relativas (incluidos los
+// directorios relativos del PATH) se resuelven desde el directorio de la shell.
+//
+// Las rutas encontradas en directorios absolutos del PATH se recuerdan en la
+// tabla hash, de modo que solo se recorre el PATH la primera vez. Si la ruta
+// recordada deja de ser un ejecutable, se olvida y se vuelve a buscar.
+//
+// Parámetros:
+//   - nombre: nombre del programa (ej: "ls") o ruta (ej: "./script.sh")
+//
+// Retorna:
+//   - string: ruta del ejecutable
+//   - error: *exec.Error con exec.ErrNotFound si no se encontró
+func (sh *Shell) buscarPrograma(nombre string) (string, error) {
+	if strings.ContainsAny(nombre, `/`+string(filepath.Separator)) {
+		return exec.LookPath(sh.rutaAbsoluta(nombre))
+	}
+
+	path, _ := sh.variable("PATH")
+	if ruta, ok := sh.hash.Buscar(path, nombre); ok {
+		if _, err := exec.LookPath(ruta); err == nil {
+			return ruta, nil
+		}
+		sh.hash.Olvidar(path, nombre)
+	}
+
+	for _, dir := range filepath.SplitList(path) {
+		ruta, err := exec.LookPath(filepath.Join(sh.directorioPath(dir), nombre))
+		if err != nil {
+			continue
+		}
+		// Un directorio relativo depende del directorio de trabajo: no se recuerda
+		if filepath.IsAbs(dir) {
+			sh.hash.Recordar(path, nombre, ruta)
+		}
+		return ruta, nil
+	}
+	return "", &exec.Error{Name: nombre, Err: exec.ErrNotFound}
+}
+
+// buscarTodos devuelve todas las rutas del PATH en las que hay un programa
+// con el nombre dado, en el orden del PATH (type -a). No usa la tabla hash.
+func (sh *Shell) buscarTodos(nombre string) []string {
+	if strings.ContainsAny(nombre, `/`+string(filepath.Separator)) {
+		if ruta, err := exec.LookPath(sh.rutaAbsoluta(nombre)); err == nil {
+			return []string{ruta}
+		}
+		return nil
+	}
+
+	var rutas []string
+	vistas := map[string]bool{}
+	path, _ := sh.variable("PATH")
+	for _, dir := range filepath.SplitList(path) {
+		ruta, err := exec.LookPath(filepath.Join(sh.directorioPath(dir), nombre))
+		if err == nil && !vistas[ruta] {
+			vistas[ruta] = true
+			rutas = append(rutas, ruta)
+		}
+	}
+	return rutas
+}
+
+// directorioPath resuelve un elemento del PATH desde el directorio de la
+// shell. Un elemento vacío es el directorio actual.
+func (sh *Shell) directorioPath(dir string) string {
+	if dir == "" {
+		dir = "."
+	}
+	return sh.rutaAbsoluta(dir)
+}
+
+// indexarPath empieza a construir en segundo plano el índice de los programas
+// del PATH actual, si no existe ya. NuevaShell lo llama al crear la shell, de
+// modo que el índice suele estar listo cuando se necesita.
+//
+// Retorna:
+//   - chan struct{}: se cierra cuando el índice está listo
+func (sh *Shell) indexarPath() chan struct{} {
+	path, _ := sh.variable("PATH")
+	var dirs []string
+	for _, dir := range filepath.SplitList(path) {
+		dirs = append(dirs, sh.directorioPath(dir))
+	}
+
+	th := sh.hash
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	th.sincronizar(path)
+	if th.listo != nil {
+		return th.listo
+	}
+	listo := make(chan struct{})
+	th.listo = listo
+
+	go func() {
+		defer close(listo)
+		indice := listarProgramas(dirs)
+
+		// Si el PATH cambió mientras tanto, este índice ya no sirve
+		th.mu.Lock()
+		defer th.mu.Unlock()
+		if th.listo == listo {
+			th.indice = indice
+		}
+	}()
+	return listo
+}
+
+// programasPath devuelve los nombres de los programas ejecutables del PATH de
+// la shell, ordenados y sin repetir. Usa el índice construido al iniciar la
+// shell, o lo construye si el PATH cambió.
+func (sh *Shell) programasPath() []string {
+	for {
+		<-sh.indexarPath()
+
+		path, _ := sh.variable("PATH")
+		sh.hash.mu.Lock()
+		vigente := sh.hash.rutas != nil && sh.hash.path == path && sh.hash.listo != nil
+		indice := sh.hash.indice
+		sh.hash.mu.Unlock()
+
+		// Si el índice se descartó mientras se construía (hash -r), repetir
+		if vigente && indice != nil {
+			return indice
+		}
+	}
+}
+
+// listarProgramas recorre los directorios del PATH y devuelve los nombres de
+// sus programas ejecutables, ordenados y sin repetir. Los directorios que no
+// pueden leerse se omiten.
+func listarProgramas(dirs []string) []string {
+	vistos := map[string]bool{}
+	nombres := []string{}
+	for _, dir := range dirs {
+		entradas, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entrada := range entradas {
+			if nombre, ok := nombreEjecutable(dir, entrada); ok && !vistos[nombre] {
+				vistos[nombre] = true
+				nombres = append(nombres, nombre)
+			}
+		}
+	}
+	sort.Strings(nombres)
+	return nombres
+}
+
+// ejecutarHash implementa el comando interno 'hash'.
+//
+// Formas soportadas:
+//   - hash: lista los programas recordados, con sus usos y su ruta
+//   - hash nombre...: busca los programas en el PATH y los recuerda
+//   - hash -r: olvida todos los programas y vuelve a indexar el PATH
+//   - hash -p ruta nombre: recuerda la ruta dada para el nombre
+//   - hash -d nombre...: olvida los programas indicados
+//   - hash -t nombre...: muestra la ruta recordada de cada programa
+//
+// Parámetros:
+//   - sh: shell cuya tabla hash se consulta o modifica
+//   - args: opciones y nombres
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: nil si se encontraron todos los nombres, error en caso contrario
+func ejecutarHash(sh *Shell, args []string, es EntradaSalida) error {
+	path, _ := sh.variable("PATH")
+
+	// PASO 1: Interpretar las opciones
+	var olvidar, mostrar bool
+	conArgumentos := len(args) > 0
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+		opcion := args[0]
+		args = args[1:]
+		if opcion == "--" {
+			break
+		}
+		switch opcion {
+		case "-r":
+			sh.hash.Vaciar()
+			sh.indexarPath()
+		case "-p":
+			if len(args) < 2 {
+				return errors.New("hash: -p: uso: hash -p ruta nombre")
+			}
+			sh.hash.Recordar(path, args[1], sh.rutaAbsoluta(args[0]))
+			args = args[2:]
+		case "-d":
+			olvidar = true
+		case "-t":
+			mostrar = true
+		default:
+			return fmt.Errorf("hash: %s: opción inválida", opcion)
+		}
+	}
+
+	// PASO 2: Sin argumentos, listar la tabla
+	if !conArgumentos {
+		if sh.hash.rutasVacias() {
+			fmt.Fprintln(es.Salida, "hash: tabla hash vacía")
+		} else {
+			sh.hash.listar(es.Salida)
+		}
+		return nil
+	}
+
+	// PASO 3: Procesar cada nombre según la operación
+	var errs []error
+	for _, nombre := range args {
+		switch {
+		case olvidar:
+			if !sh.hash.Olvidar(path, nombre) {
+				errs = append(errs, fmt.Errorf("hash: %s: no encontrado", nombre))
+			}
+		case mostrar:
+			ruta, ok := sh.hash.Buscar(path, nombre)
+			if !ok {
+				errs = append(errs, fmt.Errorf("hash: %s: no encontrado", nombre))
+			} else if len(args) > 1 {
+				fmt.Fprintf(es.Salida, "%s\t%s\n", nombre, ruta)
+			} else {
+				fmt.Fprintln(es.Salida, ruta)
+			}
+		default:
+			// Los comandos internos no se buscan en el PATH
+			if _, ok := sh.builtins.Buscar(nombre); ok {
+				continue
+			}
+			if _, err := sh.buscarPrograma(nombre); err != nil {
+				errs = append(errs, fmt.Errorf("hash: %s: no encontrado", nombre))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rutasVacias indica si no hay ningún programa recordado
+func (th *TablaHash) rutasVacias() bool {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	return len(th.rutas) == 0
+}
+
+// listar escribe los programas recordados con sus usos, ordenados por nombre,
+// en el formato de bash
+func (th *TablaHash) listar(w io.Writer) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	nombres := make([]string, 0, len(th.rutas))
+	for nombre := range th.rutas {
+		nombres = append(nombres, nombre)
+	}
+	sort.Strings(nombres)
+
+	fmt.Fprintln(w, "usos\tcomando")
+	for _, nombre := range nombres {
+		fmt.Fprintf(w, "%4d\t%s\n", th.rutas[nombre].usos, th.rutas[nombre].ruta)
+	}
+}
@@ -0,0 +1,494 @@
+// Módulo historial: Recuerda las líneas ejecutadas en el REPL, las guarda en
+// $HISTFILE entre sesiones y contiene el comando interno history. El editor
+// de línea recorre el historial con las flechas
+package goshell
+
+import (
+	"bufio"         // Para leer el archivo de historial línea por línea
+	"errors"        // Para los errores de history
+	"fmt"           // Para listar el historial
+	"io"            // Para escribir el listado en la salida del comando
+	"os"            // Para leer y escribir el archivo de historial
+	"path/filepath" // Para construir la ruta del archivo predeterminado
+	"strconv"       // Para interpretar los límites y las posiciones
+	"strings"       // Para interpretar HISTCONTROL
+	"sync"          // Para proteger el historial del acceso concurrente
+	"time"          // Para la hora de cada entrada
+)
+
+// archivoHistorial es el nombre del archivo de historial predeterminado,
+// dentro del directorio de estado de la shell
+const archivoHistorial = "history"
+
+// tamanoHistorial es el límite predeterminado de entradas del historial, el
+// mismo que el de bash
+const tamanoHistorial = 500
+
+// entradaHistorial es una línea del historial
+type entradaHistorial struct {
+	texto string    // Línea tal como se ejecutó, sin el '\n' final
+	hora  time.Time // Momento en que se ejecutó (o se leyó del archivo)
+}
+
+// Historial contiene las líneas ejecutadas en el REPL, de la más antigua a la
+// más reciente. Cada entrada tiene un número fijo, que history muestra y
+// acepta: al descartar las más antiguas por el límite de $HISTSIZE las demás
+// conservan el suyo. Lo modifica el bucle principal y lo leen el editor de
+// línea y los comandos internos de las tuberías, por eso se protege con un
+// mutex.
+type Historial struct {
+	mu          sync.Mutex
+	entradas    []entradaHistorial
+	descartadas int // Entradas descartadas del comienzo: la primera tiene el número descartadas+1
+}
+
+// controlHistorial son las opciones de $HISTCONTROL
+type controlHistorial struct {
+	ignorarEspacio    bool // ignorespace: no guardar las líneas que empiezan con espacio
+	ignorarDuplicados bool // ignoredups: no guardar una línea igual a la anterior
+	borrarDuplicados  bool // erasedups: quitar las apariciones anteriores de la línea
+}
+
+// Agregar agrega una línea al final del historial.
+//
+// Parámetros:
+//   - texto: línea ejecutada, sin el '\n' final
+//   - hora: momento en que se ejecutó
+//   - control: opciones de $HISTCONTROL
+//   - limite: máximo de entradas a conservar; negativo es sin límite
+//
+// Retorna:
+//   - bool: false si la línea no se guardó (por estar vacía o por HISTCONTROL)
+func (h *Historial) Agregar(texto string, hora time.Time, control controlHistorial, limite int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if strings.TrimSpace(texto) == "" || limite == 0 {
+		return false
+	}
+	if control.ignorarEspacio && (texto[0] == ' ' || texto[0] == '\t') {
+		return false
+	}
+	if control.ignorarDuplicados && len(h.entradas) > 0 && h.entradas[len(h.entradas)-1].texto == texto {
+		return false
+	}
+	if control.borrarDuplicados {
+		vigentes := h.entradas[:0]
+		for _, e := range h.entradas {
+			if e.texto != texto {
+				vigentes = append(vigentes, e)
+			}
+		}
+		h.entradas = vigentes
+	}
+
+	h.entradas = append(h.entradas, entradaHistorial{texto, hora})
+	h.limitar(limite)
+	return true
+}
+
+// limitar descarta las entradas más antiguas que superan el límite. Debe
+// llamarse con el mutex tomado.
+func (h *Historial) limitar(limite int) {
+	if limite < 0 || len(h.entradas) <= limite {
+		return
+	}
+	sobrantes := len(h.entradas) - limite
+	h.entradas = append([]entradaHistorial(nil), h.entradas[sobrantes:]...)
+	h.descartadas += sobrantes
+}
+
+// Cantidad devuelve cuántas entradas tiene el historial
+func (h *Historial) Cantidad() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.entradas)
+}
+
+// Entrada devuelve el texto de la entrada i (0 es la más antigua)
+func (h *Historial) Entrada(i int) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i < 0 || i >= len(h.entradas) {
+		return ""
+	}
+	return h.entradas[i].texto
+}
+
+// Borrar quita la entrada con un número de history.
+//
+// Retorna:
+//   - bool: false si no hay una entrada con ese número
+func (h *Historial) Borrar(numero int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := numero - h.descartadas - 1
+	if i < 0 || i >= len(h.entradas) {
+		return false
+	}
+	h.entradas = append(h.entradas[:i], h.entradas[i+1:]...)
+	return true
+}
+
+// Vaciar borra todas las entradas; la numeración vuelve a empezar en 1
+func (h *Historial) Vaciar() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entradas = nil
+	h.descartadas = 0
+}
+
+// copia devuelve una copia de las entradas y el número de la primera
+func (h *Historial) copia() ([]entradaHistorial, int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]entradaHistorial(nil), h.entradas...), h.descartadas + 1
+}
+
+// clonar devuelve un historial independiente con las mismas entradas y la
+// misma numeración, para una subshell
+func (h *Historial) clonar() *Historial {
+	entradas, primera := h.copia()
+	return &Historial{entradas: entradas, descartadas: primera - 1}
+}
+
+// limiteVariable interpreta una variable numérica de límite como HISTSIZE.
+// Si no está definida o no es un número, vale el valor predeterminado; un
+// valor negativo significa sin límite.
+func (sh *Shell) limiteVariable(nombre string, predeterminado int) int {
+	valor, ok := sh.variable(nombre)
+	if !ok {
+		return predeterminado
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(valor))
+	if err != nil {
+		return predeterminado
+	}
+	return max(n, -1)
+}
+
+// limitesHistorial devuelve los límites de entradas del historial en memoria
+// ($HISTSIZE, 500 por defecto) y en el archivo ($HISTFILESIZE, por defecto
+// el mismo que $HISTSIZE)
+func (sh *Shell) limitesHistorial() (int, int) {
+	tamano := sh.limiteVariable("HISTSIZE", tamanoHistorial)
+	return tamano, sh.limiteVariable("HISTFILESIZE", tamano)
+}
+
+// controlHistorial interpreta $HISTCONTROL: una lista separada por ':' de
+// ignorespace, ignoredups, ignoreboth (las dos anteriores) y erasedups
+func (sh *Shell) controlHistorial() controlHistorial {
+	var control controlHistorial
+	valor, _ := sh.variable("HISTCONTROL")
+	for _, opcion := range strings.Split(valor, ":") {
+		switch opcion {
+		case "ignorespace":
+			control.ignorarEspacio = true
+		case "ignoredups":
+			control.ignorarDuplicados = true
+		case "ignoreboth":
+			control.ignorarEspacio, control.ignorarDuplicados = true, true
+		case "erasedups":
+			control.borrarDuplicados = true
+		}
+	}
+	return control
+}
+
+// agregarHistorial guarda en el historial una línea ejecutada en el REPL,
+// según $HISTCONTROL y $HISTSIZE
+func (sh *Shell) agregarHistorial(linea string) {
+	tamano, _ := sh.limitesHistorial()
+	sh.historial.Agregar(strings.TrimRight(linea, "\r\n"), time.Now(), sh.controlHistorial(), tamano)
+}
+
+// archivoDeHistorial devuelve la ruta del archivo de historial: $HISTFILE o,
+// si no está definida, el archivo "history" del directorio de estado de la
+// shell.
+//
+// Retorna:
+//   - string: ruta del archivo
+//   - bool: false si el historial no se guarda ($HISTFILE vacía, o sin
+//     directorio de estado)
+func (sh *Shell) archivoDeHistorial() (string, bool) {
+	if ruta, ok := sh.variable("HISTFILE"); ok {
+		return sh.rutaAbsoluta(ruta), ruta != ""
+	}
+	dir, ok := sh.directorioEstado()
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(dir, archivoHistorial), true
+}
+
+// leerHistorial lee un archivo de historial y agrega sus líneas al final del
+// historial, como history -r. Las líneas "#segundos" que escribe bash (y
+// esta shell) con $HISTTIMEFORMAT dan la hora de la entrada siguiente; las
+// entradas sin hora reciben la actual.
+func (sh *Shell) leerHistorial(ruta string) error {
+	f, err := os.Open(ruta)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tamano, _ := sh.limitesHistorial()
+	var hora time.Time
+	lector := bufio.NewScanner(f)
+	lector.Buffer(nil, 1<<20)
+	for lector.Scan() {
+		linea := lector.Text()
+		if marca, ok := strings.CutPrefix(linea, "#"); ok {
+			if segundos, err := strconv.ParseInt(marca, 10, 64); err == nil {
+				hora = time.Unix(segundos, 0)
+				continue
+			}
+		}
+		if hora.IsZero() {
+			hora = time.Now()
+		}
+		sh.historial.Agregar(linea, hora, controlHistorial{}, tamano)
+		hora = time.Time{}
+	}
+	return lector.Err()
+}
+
+// escribirHistorial guarda el historial en un archivo, como history -w,
+// conservando solo las últimas $HISTFILESIZE entradas. Si $HISTTIMEFORMAT
+// está definida, cada entrada va precedida de su hora, como en bash.
+func (sh *Shell) escribirHistorial(ruta string) error {
+	entradas, _ := sh.historial.copia()
+	if _, limite := sh.limitesHistorial(); limite >= 0 && len(entradas) > limite {
+		entradas = entradas[len(entradas)-limite:]
+	}
+	_, conHora := sh.variable("HISTTIMEFORMAT")
+
+	var contenido strings.Builder
+	for _, e := range entradas {
+		if conHora {
+			fmt.Fprintf(&contenido, "#%d\n", e.hora.Unix())
+		}
+		contenido.WriteString(e.texto + "\n")
+	}
+	return os.WriteFile(ruta, []byte(contenido.String()), 0o600)
+}
+
+// cargarHistorial lee el historial de las sesiones anteriores al iniciar la
+// shell interactiva. Un archivo inexistente es un historial vacío.
+func (sh *Shell) cargarHistorial() {
+	ruta, ok := sh.archivoDeHistorial()
+	if !ok {
+		return
+	}
+	if err := sh.leerHistorial(ruta); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintln(sh.Stderr, "goshell: no se pudo leer el historial:", err)
+	}
+}
+
+// guardarHistorial escribe el historial al terminar la shell interactiva,
+// creando el directorio de estado si hace falta
+func (sh *Shell) guardarHistorial() error {
+	ruta, ok := sh.archivoDeHistorial()
+	if !ok {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(ruta), 0o700); err != nil {
+		return err
+	}
+	return sh.escribirHistorial(ruta)
+}
+
+// ejecutarHistory implementa el comando interno 'history'.
+//
+// Formas soportadas:
+//   - history [n]: lista el historial (o sus últimas n entradas) con el
+//     número de cada entrada y, si $HISTTIMEFORMAT está definida, su hora
+//     con ese formato de strftime (ej: HISTTIMEFORMAT="%F %T ")
+//   - history -c: borra el historial
+//   - history -d posición: borra una entrada; una posición negativa cuenta
+//     desde el final (-1 es la última)
+//   - history -w [archivo]: guarda el historial en el archivo ($HISTFILE
+//     por defecto)
+//   - history -r [archivo]: agrega al historial las líneas del archivo
+//
+// Parámetros:
+//   - sh: shell cuyo historial se consulta o modifica
+//   - args: opción y argumento
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: si la opción o la posición no son válidas, o el archivo no
+//     puede leerse o escribirse
+func ejecutarHistory(sh *Shell, args []string, es EntradaSalida) error {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 || !strings.HasPrefix(args[0], "-") || esNumero(args[0]) {
+		return sh.listarHistorial(args, es.Salida)
+	}
+
+	switch opcion := args[0]; opcion {
+	case "-c":
+		sh.historial.Vaciar()
+		return nil
+
+	case "-d":
+		if len(args) != 2 {
+			return errors.New("history: -d: se requiere un argumento")
+		}
+		numero, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("history: %s: posición fuera de rango", args[1])
+		}
+		if numero < 0 {
+			// Desde el final: -1 es la última entrada
+			entradas, primera := sh.historial.copia()
+			numero += primera + len(entradas)
+		}
+		if !sh.historial.Borrar(numero) {
+			return fmt.Errorf("history: %s: posición fuera de rango", args[1])
+		}
+		return nil
+
+	case "-w", "-r":
+		if len(args) > 2 {
+			return errors.New("history: demasiados argumentos")
+		}
+		ruta, ok := sh.archivoDeHistorial()
+		if len(args) == 2 {
+			ruta, ok = sh.rutaAbsoluta(args[1]), true
+		}
+		if !ok {
+			return nil
+		}
+		var err error
+		if opcion == "-w" {
+			err = sh.escribirHistorial(ruta)
+		} else {
+			err = sh.leerHistorial(ruta)
+		}
+		if err != nil {
+			return fmt.Errorf("history: %v", err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("history: %s: opción inválida\nhistory: uso: history [-c] [-d posición] [n] o history -rw [archivo]", opcion)
+	}
+}
+
+// esNumero indica si un texto es un entero, con signo opcional
+func esNumero(texto string) bool {
+	_, err := strconv.Atoi(texto)
+	return err == nil
+}
+
+// listarHistorial escribe las entradas del historial (las últimas n, si se
+// indica) en el formato de bash: número, hora opcional y línea
+func (sh *Shell) listarHistorial(args []string, salida io.Writer) error {
+	entradas, primera := sh.historial.copia()
+	if len(args) > 1 {
+		return errors.New("history: demasiados argumentos")
+	}
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return fmt.Errorf("history: %s: se requiere un argumento numérico", args[0])
+		}
+		if n < len(entradas) {
+			primera += len(entradas) - n
+			entradas = entradas[len(entradas)-n:]
+		}
+	}
+
+	formato, conHora := sh.variable("HISTTIMEFORMAT")
+	var s strings.Builder
+	for i, e := range entradas {
+		hora := ""
+		if conHora {
+			hora = formatearHora(formato, e.hora)
+		}
+		fmt.Fprintf(&s, "%5d  %s%s\n", primera+i, hora, e.texto)
+	}
+	_, err := io.WriteString(salida, s.String())
+	return err
+}
+
+// formatearHora da formato a una hora con las directivas de strftime más
+// comunes, que usa $HISTTIMEFORMAT. Las directivas desconocidas se copian
+// sin cambios.
+func formatearHora(formato string, t time.Time) string {
+	directivas := map[byte]string{
+		'a': "Mon", 'A': "Monday", 'b': "Jan", 'h': "Jan", 'B': "January",
+		'd': "02", 'H': "15", 'I': "03", 'm': "01", 'M': "04", 'S': "05",
+		'p': "PM", 'y': "06", 'Y': "2006", 'Z': "MST", 'z': "-0700",
+		'F': "2006-01-02", 'T': "15:04:05", 'D': "01/02/06", 'R': "15:04",
+		'c': "Mon Jan _2 15:04:05 2006",
+	}
+
+	var s strings.Builder
+	for i := 0; i < len(formato); i++ {
+		if formato[i] != '%' || i+1 == len(formato) {
+			s.WriteByte(formato[i])
+			continue
+		}
+		i++
+		switch c := formato[i]; c {
+		case '%':
+			s.WriteByte('%')
+		case 'n':
+			s.WriteByte('\n')
+		case 't':
+			s.WriteByte('\t')
+		case 'e':
+			fmt.Fprintf(&s, "%2d", t.Day())
+		case 'j':
+			fmt.Fprintf(&s, "%03d", t.YearDay())
+		case 's':
+			fmt.Fprintf(&s, "%d", t.Unix())
+		default:
+			if disposicion, ok := directivas[c]; ok {
+				s.WriteString(t.Format(disposicion))
+			} else {
+				s.WriteByte('%')
+				s.WriteByte(c)
+			}
+		}
+	}
+	return s.String()
+}
+
+// moverHistorial reemplaza la línea en edición por la entrada anterior
+// (delta -1) o siguiente (delta +1) del historial. La línea que se estaba
+// escribiendo se conserva y vuelve al pasar la entrada más reciente.
+//
+// Retorna:
+//   - bool: false si no hay una entrada en esa dirección
+func (ed *editorLinea) moverHistorial(delta int) bool {
+	destino := min(ed.indiceHistorial, ed.sh.historial.Cantidad()) + delta
+	if destino < 0 || destino > ed.sh.historial.Cantidad() {
+		io.WriteString(ed.sh.Stdout, "\a")
+		return false
+	}
+	ed.mostrarEntrada(destino)
+	return true
+}
+
+// mostrarEntrada reemplaza la línea en edición por una entrada del historial,
+// con el cursor al final. La cantidad de entradas representa la línea nueva,
+// que se guarda al dejarla y vuelve al regresar a ella.
+func (ed *editorLinea) mostrarEntrada(destino int) {
+	total := ed.sh.historial.Cantidad()
+	if ed.indiceHistorial >= total {
+		ed.lineaNueva = append([]rune(nil), ed.buf...)
+	}
+	ed.guardarDeshacer(accionOtra)
+	ed.indiceHistorial = destino
+	if destino >= total {
+		ed.buf = append([]rune(nil), ed.lineaNueva...)
+	} else {
+		ed.buf = []rune(ed.sh.historial.Entrada(destino))
+	}
+	ed.pos = len(ed.buf)
+}
@@ -0,0 +1,277 @@
+// Módulo interactivo: Contiene el bucle REPL de la shell interactiva
+// Muestra el prompt, lee las líneas del usuario con el editor de línea, atiende
+// las señales con trampa mientras espera y notifica la terminación de los
+// trabajos en segundo plano
+package goshell
+
+import (
+	"bufio"   // Para leer línea por línea desde la entrada estándar
+	"errors"  // Para reconocer el error centinela de exit
+	"fmt"     // Para formatear y mostrar salida
+	"io"      // Para detectar el fin de la entrada (Ctrl+D)
+	"os/user" // Para obtener información del usuario actual
+	"strings" // Para armar las notificaciones antes de escribirlas
+	"syscall" // Para reconocer las señales recibidas por la shell
+)
+
+// EjecutarREPL implementa el bucle REPL (Bucle de lectura-evaluación-impresión)
+// de la shell. Se repite hasta que el usuario ejecute "exit" o termine la entrada.
+// Lee de Stdin y escribe el prompt en Stdout; la shell se considera
+// interactiva si Stdin es una terminal.
+//
+// Retorna:
+//   - int: estado con el que debe terminar la shell (ver Finalizar)
+func (sh *Shell) EjecutarREPL() int {
+	// La shell es interactiva si lee de una terminal (afecta a set -n)
+	sh.interactiva = esTerminal(sh.Stdin)
+
+	// Una sesión interactiva continúa con la pila de directorios de la
+	// anterior y guarda la suya al terminar
+	if sh.interactiva {
+		sh.cargarPila()
+		sh.alSalir(func(estado int) int {
+			if err := sh.guardarPila(); err != nil {
+				fmt.Fprintln(sh.Stderr, "goshell: no se pudo guardar la pila de directorios:", err)
+			}
+			return estado
+		})
+
+		// Lo mismo con el historial de comandos (ver historial.go)
+		sh.cargarHistorial()
+		sh.alSalir(func(estado int) int {
+			if err := sh.guardarHistorial(); err != nil {
+				fmt.Fprintln(sh.Stderr, "goshell: no se pudo guardar el historial:", err)
+			}
+			return estado
+		})
+	}
+
+	// Crear el editor de línea, que lee la entrada del usuario desde stdin
+	// (ver editor.go). Si la shell termina mientras se edita una línea (ej:
+	// por SIGHUP), la terminal debe volver a su modo original
+	sh.editor = sh.nuevoEditor(bufio.NewReader(sh.Stdin))
+	sh.alSalir(func(estado int) int {
+		sh.editor.restaurarTerminal()
+		return estado
+	})
+
+	// Bucle infinito que implementa el REPL de la shell
+	for {
+		// PASO 1: Obtener información para mostrar en el prompt
+		
+		// Obtener el directorio de trabajo de la shell para mostrarlo en el prompt
+		wd := sh.Dir
+
+		// Obtener información del usuario actual para personalizar el prompt
+		currentUser, err := user.Current()
+		if err != nil {
+			// Si hay error obteniendo el usuario, mostrar el error pero continuar
+			fmt.Fprintln(sh.Stderr, "Error al obtener el usuario actual:", err)
+		}
+
+		// PASO 2: Mostrar el prompt y leer la entrada del usuario
+
+		// Informar los trabajos en segundo plano que terminaron desde el último
+		// prompt, igual que bash: "[1]+ Done  sleep 5"
+		sh.salidaMu.Lock()
+		sh.trabajos.NotificarTerminados(sh.Stdout)
+		sh.salidaMu.Unlock()
+		
+		// Mostrar prompt colorizado en formato "usuario:directorio goshell> "
+		// Usando códigos ANSI para colores
+		sh.mostrarPrompt(currentUser.Username, wd)
+
+		// Leer una línea completa de entrada hasta encontrar '\n' (Enter),
+		// atendiendo mientras tanto las señales con trampa (trap)
+		entrada, err := sh.leerEntrada()
+		sh.salidaMu.Lock()
+		sh.esperandoLinea = false
+		sh.salidaMu.Unlock()
+
+		// Un manejador de trap ejecutó exit, o llegó SIGHUP sin trampa
+		var salida *SalidaShell
+		if errors.As(err, &salida) {
+			return salida.Estado
+		}
+		if errors.Is(err, errInterrupcion) {
+			// Ctrl-C descarta la línea; como en bash, ejecuta la trampa INT
+			if err := sh.atenderSenal(syscall.SIGINT); errors.As(err, &salida) {
+				return salida.Estado
+			}
+			continue
+		}
+		if errors.Is(err, io.EOF) && entrada == "" {
+			// Fin de la entrada (Ctrl+D): salir con el estado del último comando
+			fmt.Fprintln(sh.Stdout)
+			return sh.ultimoEstado
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			// Si hay otro error leyendo, mostrar error y continuar el bucle
+			fmt.Fprintln(sh.Stderr, "Error al leer la entrada:", err)
+			continue
+		}
+
+		// PASO 3: Analizar y ejecutar la entrada del usuario
+		
+		// EjecutarLinea parsea la línea para extraer el comando, sus argumentos
+		// y el sufijo &, ejecuta el comando (interno o externo) y actualiza $?.
+		// Las líneas vacías o con solo espacios se ignoran
+		// Los errores se muestran sin terminar la shell, salvo exit o set -e
+		// El texto escrito en el editor externo (tecla v del modo vi) puede
+		// tener varias líneas, que se ejecutan una por una como en un script
+		for _, linea := range strings.SplitAfter(entrada, "\n") {
+			if linea == "" {
+				continue
+			}
+			// En la shell interactiva, cada línea pasa por la expansión del
+			// historial (!!, !$...) y se guarda en él antes de ejecutarla,
+			// para que history la muestre
+			linea, ejecutar := sh.lineaDelHistorial(linea)
+			if !ejecutar {
+				continue
+			}
+			err = sh.EjecutarLinea(linea)
+			if salida, termina := sh.informarError(err); termina {
+				return salida.Estado
+			}
+		}
+
+		// PASO 4: Atender las señales que llegaron durante el comando
+		if err := sh.atenderSenalesPendientes(); errors.As(err, &salida) {
+			return salida.Estado
+		}
+		
+		// El bucle continúa para procesar el siguiente comando
+		// Solo se rompe cuando se ejecuta el comando interno "exit"
+	}
+}
+
+// lecturaEntrada es el resultado de leer una línea en segundo plano
+type lecturaEntrada struct {
+	linea string
+	err   error
+}
+
+// leerEntrada lee una línea de la entrada del usuario con el editor de línea.
+// La lectura se hace en una goroutine para que el bucle REPL pueda seguir
+// atendiendo las señales con trampa mientras el usuario escribe; el manejador
+// se ejecuta aquí, en el bucle principal, y después se vuelve a dibujar el
+// prompt con lo que el usuario llevaba escrito.
+//
+// Solo se lanza una lectura por llamada, así la goroutine no queda leyendo
+// la entrada mientras se ejecuta un comando que también la necesita.
+//
+// Retorna:
+//   - string: la línea leída (incluye el '\n' final)
+//   - error: error de lectura, errInterrupcion si el usuario pulsó Ctrl-C, o
+//            *SalidaShell si una señal termina la shell
+func (sh *Shell) leerEntrada() (string, error) {
+	resultado := make(chan lecturaEntrada, 1)
+	go func() {
+		linea, err := sh.editor.leerLinea()
+		resultado <- lecturaEntrada{linea, err}
+	}()
+
+	for {
+		select {
+		case r := <-resultado:
+			return r.linea, r.err
+		case s := <-sh.senales:
+			senal, ok := s.(syscall.Signal)
+			if !ok {
+				continue
+			}
+			if err := sh.atenderSenal(senal); err != nil {
+				return "", err
+			}
+			sh.salidaMu.Lock()
+			sh.saltarLinea()
+			sh.redibujarPrompt()
+			sh.salidaMu.Unlock()
+		}
+	}
+}
+
+// mostrarPrompt muestra el prompt colorizado de la shell
+// Formato: usuario:directorio goshell>
+// Si la pila de directorios no está vacía, se muestra su profundidad después
+// del directorio: usuario:directorio [2] goshell>
+func (sh *Shell) mostrarPrompt(usuario, directorio string) {
+	// Definir códigos de color ANSI
+	const (
+		ColorReset    = "\033[0m"
+		ColorVerde    = "\033[32m"
+		ColorAzul     = "\033[34m"
+		ColorMagenta  = "\033[35m"
+		ColorCian     = "\033[36m"
+		ColorNegrita  = "\033[1m"
+	)
+
+	// Acortar el directorio si es muy largo (mostrar solo los últimos 40 caracteres)
+	dirMostrar := directorio
+	if len(directorio) > 40 {
+		dirMostrar = "..." + directorio[len(directorio)-37:]
+	}
+
+	// Profundidad de la pila de directorios, en cian
+	pila := ""
+	if len(sh.pila) > 0 {
+		pila = fmt.Sprintf(" %s[%d]%s", ColorCian, len(sh.pila), ColorReset)
+	}
+
+	// Construir prompt colorizado: usuario en verde, directorio en azul, "goshell>" en magenta
+	prompt := fmt.Sprintf("%s%s%s%s:%s%s%s%s%s %s%sgoshell>%s ",
+		ColorVerde, ColorNegrita, usuario, ColorReset,
+		ColorAzul, ColorNegrita, dirMostrar, ColorReset, pila,
+		ColorMagenta, ColorNegrita, ColorReset)
+
+	// Guardar el prompt para poder redibujarlo tras una notificación inmediata
+	sh.salidaMu.Lock()
+	defer sh.salidaMu.Unlock()
+	sh.promptActual = prompt
+	sh.esperandoLinea = true
+	fmt.Fprint(sh.Stdout, prompt)
+}
+
+// notificarTrabajoInmediato informa la terminación de un trabajo en el momento
+// en que ocurre (opción "set -b"). Se llama desde la goroutine que esperaba al
+// proceso; si la shell estaba esperando una línea, vuelve a dibujar el prompt.
+func (sh *Shell) notificarTrabajoInmediato(t *Trabajo) {
+	sh.salidaMu.Lock()
+	defer sh.salidaMu.Unlock()
+
+	var aviso strings.Builder
+	if !sh.trabajos.Notificar(&aviso, t) {
+		return // Ya se había notificado
+	}
+
+	// Si el prompt está en pantalla, saltar a una línea nueva para no escribir
+	// sobre él y dibujarlo de nuevo al final
+	if sh.esperandoLinea {
+		sh.saltarLinea()
+		fmt.Fprint(sh.Stdout, aviso.String())
+		sh.redibujarPrompt()
+		return
+	}
+	fmt.Fprint(sh.Stdout, aviso.String())
+}
+
+// saltarLinea pasa a una línea nueva debajo del prompt, sin tapar lo que el
+// usuario lleva escrito. Debe llamarse con sh.salidaMu tomado.
+func (sh *Shell) saltarLinea() {
+	if sh.editor != nil && sh.editor.editando {
+		sh.editor.saltarLinea()
+		return
+	}
+	fmt.Fprint(sh.Stdout, "\n")
+}
+
+// redibujarPrompt vuelve a mostrar el prompt después de un aviso, junto con
+// la línea que se estaba editando. Debe llamarse con sh.salidaMu tomado.
+func (sh *Shell) redibujarPrompt() {
+	if sh.editor != nil && sh.editor.editando {
+		sh.editor.refrescar()
+		return
+	}
+	fmt.Fprint(sh.Stdout, sh.promptActual)
+}
@@ -0,0 +1,273 @@
+// Módulo opciones: Contiene las opciones de la shell y los comandos internos
+// set y shopt. Las opciones de set pueden activarse por letra (set -b) o por
+// nombre (set -o notify); las de shopt, por nombre (shopt -s autocd)
+package goshell
+
+import (
+	"errors"      // Para los errores de uso de shopt
+	"fmt"         // Para mostrar el listado de opciones y los errores
+	"io"          // Para escribir el listado en la salida del comando
+	"sync/atomic" // Las opciones se leen desde goroutines de segundo plano
+)
+
+// Índices de las opciones disponibles en la shell, en el orden en que se listan
+const (
+	// optAutocd (shopt -s autocd): en la shell interactiva, un nombre de
+	// directorio escrito como comando cambia a ese directorio
+	optAutocd = iota
+
+	// optBgStdin (set -o bgstdin): los trabajos en segundo plano leen de la
+	// terminal en lugar de /dev/null. Solo para comandos que realmente lo necesiten
+	optBgStdin
+
+	// optCdspell (shopt -s cdspell): en la shell interactiva, cd corrige los
+	// errores pequeños en los componentes del directorio
+	optCdspell
+
+	// optErrexit (set -e): la shell termina cuando un comando falla
+	optErrexit
+
+	// optHistverify (shopt -s histverify): una línea con expansiones del
+	// historial vuelve al editor para revisarla, en lugar de ejecutarse
+	optHistverify
+
+	// optNoclobber (set -C): ">" no sobrescribe archivos existentes; ">|" sí
+	optNoclobber
+
+	// optNoexec (set -n): lee los comandos sin ejecutarlos, para comprobar la
+	// sintaxis. Se ignora en una shell interactiva
+	optNoexec
+
+	// optNotify (set -b): notifica la terminación de trabajos en segundo plano
+	// en cuanto ocurre, en lugar de esperar al siguiente prompt
+	optNotify
+
+	// optNounset (set -u): expandir una variable sin definir es un error
+	optNounset
+
+	// optPipefail (set -o pipefail): el estado de una tubería es el del último
+	// comando que falló, en lugar del estado del último comando
+	optPipefail
+
+	// optVi (set -o vi): el editor de línea usa los atajos de vi en lugar de
+	// los de emacs
+	optVi
+
+	// optXtrace (set -x): muestra cada comando expandido, precedido por $PS4
+	optXtrace
+
+	// cantidadOpciones no es una opción: es el tamaño de la tabla
+	cantidadOpciones
+)
+
+// opcion describe una opción de la shell modificable con el comando set o,
+// si es una opción de shopt, con el comando shopt.
+type opcion struct {
+	nombre string // Nombre largo usado con set -o / set +o o con shopt
+	letra  byte   // Letra usada con set -X / set +X (0 si no tiene)
+	shopt  bool   // true si se modifica con shopt en lugar de set
+}
+
+// opcionesShell es la tabla de todas las opciones, indexada por las constantes opt*
+var opcionesShell = [cantidadOpciones]opcion{
+	optAutocd:     {nombre: "autocd", shopt: true},
+	optBgStdin:    {nombre: "bgstdin"},
+	optCdspell:    {nombre: "cdspell", shopt: true},
+	optErrexit:    {nombre: "errexit", letra: 'e'},
+	optHistverify: {nombre: "histverify", shopt: true},
+	optNoclobber:  {nombre: "noclobber", letra: 'C'},
+	optNoexec:     {nombre: "noexec", letra: 'n'},
+	optNotify:     {nombre: "notify", letra: 'b'},
+	optNounset:    {nombre: "nounset", letra: 'u'},
+	optPipefail:   {nombre: "pipefail"},
+	optVi:         {nombre: "vi"},
+	optXtrace:     {nombre: "xtrace", letra: 'x'},
+}
+
+// Opciones guarda el estado de las opciones de una shell, indexado por las
+// constantes opt*. Cada opción es atómica porque las goroutines que esperan
+// a los trabajos en segundo plano también las consultan.
+type Opciones [cantidadOpciones]atomic.Bool
+
+// ejecutarSet implementa el comando interno 'set' para modificar las opciones.
+//
+// Formas soportadas:
+//   - set -X / set +X: activa o desactiva la opción cuya letra es X (ej: set -b)
+//   - set -o nombre / set +o nombre: activa o desactiva la opción por nombre
+//   - set -o / set: lista todas las opciones con su estado
+//
+// Parámetros:
+//   - sh: shell cuyas opciones se modifican
+//   - args: slice de argumentos del comando set
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: nil si todas las opciones eran válidas, error en caso contrario
+func ejecutarSet(sh *Shell, args []string, es EntradaSalida) error {
+	if len(args) == 0 || (len(args) == 1 && args[0] == "-o") {
+		listarOpciones(sh, es.Salida)
+		return nil
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') {
+			return fmt.Errorf("set: %s: opción inválida", arg)
+		}
+		activar := arg[0] == '-'
+
+		// Forma larga: set -o nombre / set +o nombre
+		if arg[1:] == "o" {
+			if i+1 >= len(args) {
+				listarOpciones(sh, es.Salida)
+				return nil
+			}
+			i++
+			op := buscarOpcionPorNombre(args[i], false)
+			if op < 0 {
+				return fmt.Errorf("set: %s: nombre de opción inválido", args[i])
+			}
+			sh.opciones[op].Store(activar)
+			continue
+		}
+
+		// Forma corta: una o más letras agrupadas (ej: set -b)
+		for j := 1; j < len(arg); j++ {
+			op := buscarOpcionPorLetra(arg[j])
+			if op < 0 {
+				return fmt.Errorf("set: %c%c: opción inválida", arg[0], arg[j])
+			}
+			sh.opciones[op].Store(activar)
+		}
+	}
+	return nil
+}
+
+// listarOpciones muestra todas las opciones de set en el formato de "set -o"
+// de bash
+func listarOpciones(sh *Shell, w io.Writer) {
+	for i, op := range opcionesShell {
+		if op.shopt {
+			continue
+		}
+		estado := "off"
+		if sh.opciones[i].Load() {
+			estado = "on"
+		}
+		fmt.Fprintf(w, "%-15s\t%s\n", op.nombre, estado)
+	}
+}
+
+// buscarOpcionPorNombre devuelve el índice de la opción de set (o de shopt,
+// si shopt es true) con el nombre dado, o -1 si no existe
+func buscarOpcionPorNombre(nombre string, shopt bool) int {
+	for i, op := range opcionesShell {
+		if op.nombre == nombre && op.shopt == shopt {
+			return i
+		}
+	}
+	return -1
+}
+
+// buscarOpcionPorLetra devuelve el índice de la opción con la letra dada, o
+// -1 si no existe
+func buscarOpcionPorLetra(letra byte) int {
+	for i, op := range opcionesShell {
+		if op.letra != 0 && op.letra == letra {
+			return i
+		}
+	}
+	return -1
+}
+
+// ejecutarShopt implementa el comando interno 'shopt' para modificar las
+// opciones de shopt (o, con -o, las de set -o).
+//
+// Formas soportadas:
+//   - shopt -s nombre... / shopt -u nombre...: activa o desactiva las opciones
+//   - shopt [nombre...]: muestra el estado de las opciones (todas si se omiten)
+//   - shopt -p [nombre...]: las muestra como comandos shopt que las restauran
+//   - shopt -q nombre...: no muestra nada; el estado es 0 si están todas activas
+//   - shopt -o ...: trabaja con las opciones de set -o en lugar de las de shopt
+//
+// Parámetros:
+//   - sh: shell cuyas opciones se modifican
+//   - args: opciones del comando y nombres
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: si algún nombre no es válido; al consultar, EstadoSalida(1) si
+//            alguna opción está desactivada
+func ejecutarShopt(sh *Shell, args []string, es EntradaSalida) error {
+	letras, nombres, err := parsearLetras("shopt", args, "opqsu")
+	if err != nil {
+		return fmt.Errorf("%v\nshopt: uso: shopt [-pqsu] [-o] [nombre ...]", err)
+	}
+	if letras['s'] && letras['u'] {
+		return errors.New("shopt: no se puede activar y desactivar opciones a la vez")
+	}
+	deSet := letras['o']
+
+	// PASO 1: Interpretar los nombres; sin nombres se usan todas las opciones
+	var indices []int
+	for _, nombre := range nombres {
+		op := buscarOpcionPorNombre(nombre, !deSet)
+		if op < 0 {
+			return fmt.Errorf("shopt: %s: nombre de opción inválido", nombre)
+		}
+		indices = append(indices, op)
+	}
+
+	// PASO 2: Activar o desactivar
+	if letras['s'] || letras['u'] {
+		for _, op := range indices {
+			sh.opciones[op].Store(letras['s'])
+		}
+		if len(indices) > 0 {
+			return nil
+		}
+	}
+
+	// PASO 3: Consultar. Sin nombres, -s y -u filtran las activas o inactivas
+	if len(indices) == 0 {
+		for i, op := range opcionesShell {
+			activa := sh.opciones[i].Load()
+			if op.shopt != deSet && (!letras['s'] || activa) && (!letras['u'] || !activa) {
+				indices = append(indices, i)
+			}
+		}
+	}
+
+	estado := 0
+	for _, op := range indices {
+		activa := sh.opciones[op].Load()
+		texto := "on"
+		if !activa {
+			estado, texto = 1, "off"
+		}
+		switch {
+		case letras['q']:
+		case letras['p']:
+			fmt.Fprintf(es.Salida, "shopt %s %s\n", formaShopt(activa, deSet), opcionesShell[op].nombre)
+		default:
+			fmt.Fprintf(es.Salida, "%-15s\t%s\n", opcionesShell[op].nombre, texto)
+		}
+	}
+	if estado != 0 && len(nombres) > 0 {
+		return EstadoSalida(estado)
+	}
+	return nil
+}
+
+// formaShopt devuelve las opciones de shopt que fijan una opción en el
+// estado dado, para "shopt -p"
+func formaShopt(activa, deSet bool) string {
+	forma := "-u"
+	if activa {
+		forma = "-s"
+	}
+	if deSet {
+		forma += " -o"
+	}
+	return forma
+}
@@ -0,0 +1,325 @@
+// Módulo pila: Pila de directorios de la shell con los comandos internos
+// pushd, popd y dirs, igual que en bash. La pila se guarda al terminar una
+// sesión interactiva y se recupera en la siguiente
+package goshell
+
+import (
+	"errors"        // Para los errores de pila vacía
+	"fmt"           // Para mostrar la pila
+	"io"            // Para escribir la pila en la salida del comando
+	"os"            // Para leer y escribir el archivo de la pila
+	"path/filepath" // Para construir la ruta del archivo de estado
+	"strconv"       // Para interpretar los índices +N y -N
+	"strings"       // Para abreviar el home y leer el archivo
+)
+
+// archivoPila es el nombre del archivo, dentro del directorio de estado de la
+// shell, en el que se guarda la pila de directorios entre sesiones
+const archivoPila = "dirs"
+
+// listaDirectorios devuelve la pila completa tal como la muestra dirs: el
+// directorio actual en la posición 0, seguido de los directorios guardados
+func (sh *Shell) listaDirectorios() []string {
+	return append([]string{sh.Dir}, sh.pila...)
+}
+
+// indicePila interpreta un argumento +N o -N de pushd, popd o dirs: +N cuenta
+// desde la izquierda de la lista que muestra dirs (empezando en 0) y -N desde
+// la derecha.
+//
+// Parámetros:
+//   - arg: argumento a interpretar
+//   - total: cantidad de elementos de la pila, incluido el directorio actual
+//
+// Retorna:
+//   - int: posición en la lista de dirs
+//   - bool: true si el argumento tiene la forma +N o -N
+//   - error: si la posición está fuera de la pila
+func indicePila(arg string, total int) (int, bool, error) {
+	if len(arg) < 2 || (arg[0] != '+' && arg[0] != '-') {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(arg[1:])
+	if err != nil || n < 0 {
+		return 0, false, nil
+	}
+	if n >= total {
+		return 0, true, fmt.Errorf("%s: índice de la pila de directorios fuera de rango", arg)
+	}
+	if arg[0] == '-' {
+		n = total - 1 - n
+	}
+	return n, true, nil
+}
+
+// abreviarHome sustituye el directorio $HOME al principio de una ruta por ~
+func (sh *Shell) abreviarHome(ruta string) string {
+	home, ok := sh.variable("HOME")
+	if !ok || home == "" || home == string(filepath.Separator) {
+		return ruta
+	}
+	if ruta == home {
+		return "~"
+	}
+	if resto, ok := strings.CutPrefix(ruta, home+string(filepath.Separator)); ok {
+		return "~" + string(filepath.Separator) + resto
+	}
+	return ruta
+}
+
+// mostrarPila escribe la pila de directorios en una línea, con ~ en lugar de
+// $HOME, como hacen pushd y popd después de modificarla
+func (sh *Shell) mostrarPila(w io.Writer) {
+	lista := sh.listaDirectorios()
+	for i, dir := range lista {
+		lista[i] = sh.abreviarHome(dir)
+	}
+	fmt.Fprintln(w, strings.Join(lista, " "))
+}
+
+// ejecutarPushd implementa el comando interno 'pushd'.
+//
+// Formas soportadas:
+//   - pushd dir: guarda el directorio actual en la pila y cambia a dir
+//   - pushd: intercambia los dos primeros directorios de la pila
+//   - pushd +N / pushd -N: rota la pila para que el directorio N (contado
+//     desde la izquierda o la derecha de dirs) quede el primero, y cambia a él
+//
+// Parámetros:
+//   - sh: shell cuya pila se modifica
+//   - args: directorio o índice
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: si la pila está vacía, el índice no existe o no se pudo cambiar de directorio
+func ejecutarPushd(sh *Shell, args []string, es EntradaSalida) error {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) > 1 {
+		return errors.New("pushd: demasiados argumentos")
+	}
+
+	lista := sh.listaDirectorios()
+	switch {
+	case len(args) == 0:
+		// Intercambiar el directorio actual con el primero de la pila
+		if len(sh.pila) == 0 {
+			return errors.New("pushd: no hay otro directorio")
+		}
+		if err := sh.cambiarDirectorio(sh.pila[0], false); err != nil {
+			return fmt.Errorf("pushd: %w", err)
+		}
+		sh.pila[0] = lista[0]
+
+	default:
+		n, esIndice, err := indicePila(args[0], len(lista))
+		if err != nil {
+			return fmt.Errorf("pushd: %w", err)
+		}
+		if esIndice {
+			// Rotar la lista para que el elemento n quede el primero
+			rotada := append(lista[n:len(lista):len(lista)], lista[:n]...)
+			if err := sh.cambiarDirectorio(rotada[0], false); err != nil {
+				return fmt.Errorf("pushd: %w", err)
+			}
+			sh.pila = rotada[1:]
+			break
+		}
+
+		if err := sh.cambiarDirectorio(args[0], false); err != nil {
+			return fmt.Errorf("pushd: %w", err)
+		}
+		sh.pila = lista
+	}
+
+	sh.mostrarPila(es.Salida)
+	return nil
+}
+
+// ejecutarPopd implementa el comando interno 'popd'.
+//
+// Formas soportadas:
+//   - popd: quita el primer directorio de la pila y cambia al siguiente
+//   - popd +N / popd -N: quita el directorio N (contado desde la izquierda o
+//     la derecha de dirs) sin cambiar de directorio, salvo que sea el actual
+//
+// Parámetros:
+//   - sh: shell cuya pila se modifica
+//   - args: índice opcional
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: si la pila está vacía o el índice no existe
+func ejecutarPopd(sh *Shell, args []string, es EntradaSalida) error {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) > 1 {
+		return errors.New("popd: demasiados argumentos")
+	}
+	if len(sh.pila) == 0 {
+		return errors.New("popd: la pila de directorios está vacía")
+	}
+
+	lista := sh.listaDirectorios()
+	n := 0
+	if len(args) == 1 {
+		var esIndice bool
+		var err error
+		n, esIndice, err = indicePila(args[0], len(lista))
+		if err != nil {
+			return fmt.Errorf("popd: %w", err)
+		}
+		if !esIndice {
+			return fmt.Errorf("popd: %s: argumento inválido\npopd: uso: popd [+N | -N]", args[0])
+		}
+	}
+
+	if n == 0 {
+		// Quitar el directorio actual: cambiar al siguiente de la pila
+		if err := sh.cambiarDirectorio(sh.pila[0], false); err != nil {
+			return fmt.Errorf("popd: %w", err)
+		}
+		sh.pila = sh.pila[1:]
+	} else {
+		sh.pila = append(sh.pila[:n-1:n-1], sh.pila[n:]...)
+	}
+
+	sh.mostrarPila(es.Salida)
+	return nil
+}
+
+// ejecutarDirs implementa el comando interno 'dirs'.
+//
+// Formas soportadas:
+//   - dirs: muestra la pila en una línea, con ~ en lugar de $HOME
+//   - dirs -v: un directorio por línea, precedido de su índice
+//   - dirs -p: un directorio por línea, sin índice
+//   - dirs -l: muestra las rutas completas, sin abreviar con ~
+//   - dirs -c: vacía la pila (el directorio actual se conserva)
+//   - dirs +N / dirs -N: muestra solo el directorio N
+//
+// Parámetros:
+//   - sh: shell cuya pila se muestra
+//   - args: opciones e índice
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: si una opción no es válida o el índice no existe
+func ejecutarDirs(sh *Shell, args []string, es EntradaSalida) error {
+	lista := sh.listaDirectorios()
+	var completas, porLinea, conIndice bool
+	elegido := -1
+
+	for _, arg := range args {
+		if n, esIndice, err := indicePila(arg, len(lista)); esIndice || err != nil {
+			if err != nil {
+				return fmt.Errorf("dirs: %w", err)
+			}
+			elegido = n
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") || len(arg) < 2 {
+			return fmt.Errorf("dirs: %s: argumento inválido\ndirs: uso: dirs [-clpv] [+N] [-N]", arg)
+		}
+		for _, letra := range arg[1:] {
+			switch letra {
+			case 'c':
+				sh.pila = nil
+				return nil
+			case 'l':
+				completas = true
+			case 'p':
+				porLinea = true
+			case 'v':
+				porLinea, conIndice = true, true
+			default:
+				return fmt.Errorf("dirs: -%c: opción inválida\ndirs: uso: dirs [-clpv] [+N] [-N]", letra)
+			}
+		}
+	}
+
+	for i, dir := range lista {
+		if !completas {
+			lista[i] = sh.abreviarHome(dir)
+		}
+	}
+
+	switch {
+	case elegido >= 0:
+		fmt.Fprintln(es.Salida, lista[elegido])
+	case conIndice:
+		for i, dir := range lista {
+			fmt.Fprintf(es.Salida, "%2d  %s\n", i, dir)
+		}
+	case porLinea:
+		for _, dir := range lista {
+			fmt.Fprintln(es.Salida, dir)
+		}
+	default:
+		fmt.Fprintln(es.Salida, strings.Join(lista, " "))
+	}
+	return nil
+}
+
+// directorioEstado devuelve el directorio en el que la shell guarda su estado
+// entre sesiones: $XDG_STATE_HOME/goshell, o ~/.local/state/goshell si la
+// variable no está definida.
+//
+// Retorna:
+//   - string: ruta del directorio (puede no existir todavía)
+//   - bool: false si no hay ni $XDG_STATE_HOME ni $HOME
+func (sh *Shell) directorioEstado() (string, bool) {
+	if dir, ok := sh.variable("XDG_STATE_HOME"); ok && filepath.IsAbs(dir) {
+		return filepath.Join(dir, "goshell"), true
+	}
+	if home, ok := sh.variable("HOME"); ok && home != "" {
+		return filepath.Join(home, ".local", "state", "goshell"), true
+	}
+	return "", false
+}
+
+// guardarPila escribe la pila de directorios (sin el directorio actual) en el
+// archivo de estado, un directorio por línea. Una pila vacía borra el archivo.
+func (sh *Shell) guardarPila() error {
+	dir, ok := sh.directorioEstado()
+	if !ok {
+		return nil
+	}
+	ruta := filepath.Join(dir, archivoPila)
+	if len(sh.pila) == 0 {
+		if err := os.Remove(ruta); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(ruta, []byte(strings.Join(sh.pila, "\n")+"\n"), 0o600)
+}
+
+// cargarPila recupera la pila de directorios guardada por la sesión anterior.
+// Los directorios que ya no existen se descartan.
+func (sh *Shell) cargarPila() {
+	dir, ok := sh.directorioEstado()
+	if !ok {
+		return
+	}
+	contenido, err := os.ReadFile(filepath.Join(dir, archivoPila))
+	if err != nil {
+		return
+	}
+
+	sh.pila = nil
+	for _, linea := range strings.Split(string(contenido), "\n") {
+		if !filepath.IsAbs(linea) {
+			continue
+		}
+		if info, err := os.Stat(linea); err == nil && info.IsDir() {
+			sh.pila = append(sh.pila, linea)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+//go:build unix
+
+// Módulo procesos (Unix): Operaciones sobre procesos que dependen del sistema
+// operativo, como los grupos de procesos y el envío de señales
+package goshell
+
+import (
+	"io/fs"         // Para inspeccionar los archivos del PATH
+	"os"            // Para seguir los enlaces simbólicos del PATH
+	"os/exec"       // Para configurar los atributos del proceso hijo
+	"path/filepath" // Para construir la ruta de los enlaces simbólicos
+	"syscall"       // Para grupos de procesos y señales POSIX
+)
+
+// tablaSenales asocia el nombre de cada señal (sin el prefijo SIG) con su valor
+var tablaSenales = map[string]syscall.Signal{
+	"HUP":    syscall.SIGHUP,
+	"INT":    syscall.SIGINT,
+	"QUIT":   syscall.SIGQUIT,
+	"ILL":    syscall.SIGILL,
+	"TRAP":   syscall.SIGTRAP,
+	"ABRT":   syscall.SIGABRT,
+	"BUS":    syscall.SIGBUS,
+	"FPE":    syscall.SIGFPE,
+	"KILL":   syscall.SIGKILL,
+	"USR1":   syscall.SIGUSR1,
+	"SEGV":   syscall.SIGSEGV,
+	"USR2":   syscall.SIGUSR2,
+	"PIPE":   syscall.SIGPIPE,
+	"ALRM":   syscall.SIGALRM,
+	"TERM":   syscall.SIGTERM,
+	"CHLD":   syscall.SIGCHLD,
+	"CONT":   syscall.SIGCONT,
+	"STOP":   syscall.SIGSTOP,
+	"TSTP":   syscall.SIGTSTP,
+	"TTIN":   syscall.SIGTTIN,
+	"TTOU":   syscall.SIGTTOU,
+	"URG":    syscall.SIGURG,
+	"XCPU":   syscall.SIGXCPU,
+	"XFSZ":   syscall.SIGXFSZ,
+	"VTALRM": syscall.SIGVTALRM,
+	"PROF":   syscall.SIGPROF,
+	"WINCH":  syscall.SIGWINCH,
+	"IO":     syscall.SIGIO,
+	"SYS":    syscall.SIGSYS,
+}
+
+// senalContinuar reanuda un proceso detenido (SIGCONT)
+const senalContinuar = syscall.SIGCONT
+
+// prepararGrupoProcesos hace que el proceso hijo se ejecute en su propio grupo
+// de procesos, cuyo identificador (PGID) es igual a su PID, o en el grupo pgid
+// si es distinto de 0 (el resto de los comandos de una tubería). Así una señal
+// enviada a un trabajo llega a todos los procesos que este haya creado.
+//
+// Retorna:
+//   - bool: true si el proceso tendrá su propio grupo
+func prepararGrupoProcesos(cmd *exec.Cmd, pgid int) bool {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.SysProcAttr.Pgid = pgid
+	return true
+}
+
+// enviarSenal envía una señal a un proceso o, si grupo es true, a todo el
+// grupo de procesos cuyo PGID es pid (kill con PID negativo).
+func enviarSenal(pid int, grupo bool, senal syscall.Signal) error {
+	if grupo {
+		return syscall.Kill(-pid, senal)
+	}
+	return syscall.Kill(pid, senal)
+}
+
+// senalProceso envía una señal a un proceso iniciado por la shell. A
+// diferencia de enviarSenal, usa el os.Process del proceso, que retorna
+// os.ErrProcessDone si ya fue retirado con Wait en lugar de enviar la señal
+// a otro proceso con el mismo PID.
+func senalProceso(p *os.Process, senal syscall.Signal) error {
+	return p.Signal(senal)
+}
+
+// nombreEjecutable indica si un archivo de un directorio del PATH es un
+// programa ejecutable y devuelve el nombre con el que se invoca. Los enlaces
+// simbólicos se siguen hasta su destino.
+//
+// Parámetros:
+//   - dir: directorio del PATH que contiene el archivo
+//   - entrada: archivo leído del directorio
+//
+// Retorna:
+//   - string: nombre del comando (el del archivo)
+//   - bool: true si es un archivo ejecutable
+func nombreEjecutable(dir string, entrada fs.DirEntry) (string, bool) {
+	info, err := entrada.Info()
+	if err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		info, err = os.Stat(filepath.Join(dir, entrada.Name()))
+	}
+	if err != nil || !info.Mode().IsRegular() || info.Mode()&0o111 == 0 {
+		return "", false
+	}
+	return entrada.Name(), true
+}
@@ -0,0 +1,75 @@
+//go:build windows
+
+// Módulo procesos (Windows): Versión reducida de las operaciones sobre procesos.
+// Windows no tiene grupos de procesos POSIX y solo permite terminar procesos
+package goshell
+
+import (
+	"fmt"           // Para informar las señales no soportadas
+	"io/fs"         // Para inspeccionar los archivos del PATH
+	"os"            // Para localizar y terminar procesos
+	"os/exec"       // Para mantener la misma firma que la versión Unix
+	"path/filepath" // Para obtener la extensión de los programas
+	"strings"       // Para comparar extensiones sin distinguir mayúsculas
+	"syscall"       // Para los valores de las señales
+)
+
+// tablaSenales contiene las señales que syscall define en Windows
+var tablaSenales = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"TERM": syscall.SIGTERM,
+}
+
+// senalContinuar no existe en Windows; se usa la señal 0, que no hace nada
+const senalContinuar = syscall.Signal(0)
+
+// prepararGrupoProcesos no hace nada en Windows: no existen grupos de procesos
+func prepararGrupoProcesos(cmd *exec.Cmd, pgid int) bool {
+	return false
+}
+
+// senalProceso termina un proceso iniciado por la shell, a través de su
+// os.Process, igual que enviarSenal con un PID
+func senalProceso(p *os.Process, senal syscall.Signal) error {
+	if senal == 0 {
+		return nil
+	}
+	if senal != syscall.SIGKILL && senal != syscall.SIGTERM {
+		return fmt.Errorf("señal %d no soportada en Windows", int(senal))
+	}
+	return p.Kill()
+}
+
+// enviarSenal termina el proceso indicado. Windows solo permite matar procesos,
+// por lo que cualquier otra señal produce un error.
+func enviarSenal(pid int, grupo bool, senal syscall.Signal) error {
+	if senal == 0 {
+		return nil
+	}
+	if senal != syscall.SIGKILL && senal != syscall.SIGTERM {
+		return fmt.Errorf("señal %d no soportada en Windows", int(senal))
+	}
+	proceso, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return proceso.Kill()
+}
+
+// nombreEjecutable indica si un archivo de un directorio del PATH es un
+// programa ejecutable, según su extensión, y devuelve el nombre con el que se
+// invoca (sin la extensión: "git.exe" se invoca como "git").
+func nombreEjecutable(dir string, entrada fs.DirEntry) (string, bool) {
+	if entrada.IsDir() {
+		return "", false
+	}
+	ext := filepath.Ext(entrada.Name())
+	switch strings.ToLower(ext) {
+	case ".exe", ".com", ".bat", ".cmd":
+		return strings.TrimSuffix(entrada.Name(), ext), true
+	}
+	return "", false
+}
@@ -0,0 +1,250 @@
+// Módulo resolución: Decide cómo se interpreta un nombre de comando, en el
+// mismo orden que sigue EjecutarComando, y contiene los comandos internos que
+// lo consultan o lo saltan: type, command y builtin
+package goshell
+
+import (
+	"errors"  // Para combinar los errores de type y command
+	"fmt"     // Para describir los comandos
+	"io"      // Para escribir las descripciones en la salida del comando
+	"strings" // Para interpretar las opciones
+)
+
+// Tipos de comando, con los nombres que muestra "type -t" (los de bash, para
+// que los scripts puedan compararlos)
+const (
+	tipoInterno = "builtin"
+	tipoArchivo = "file"
+)
+
+// resolucion es una de las formas en que puede interpretarse un nombre de
+// comando
+type resolucion struct {
+	tipo    string  // tipoInterno o tipoArchivo
+	interno Builtin // Comando interno, si el tipo es tipoInterno
+	ruta    string  // Ruta del programa, si el tipo es tipoArchivo
+	enHash  bool    // true si la ruta ya estaba en la tabla hash
+}
+
+// resolverComando busca un nombre de comando en el orden en que lo ejecuta la
+// shell: primero los comandos internos y después los programas, tomados de la
+// tabla hash o del PATH. Los alias y las funciones irían antes que los
+// comandos internos, como en bash, pero la shell todavía no los tiene.
+//
+// Parámetros:
+//   - nombre: nombre del comando
+//   - todas: true para devolver todas las interpretaciones (type -a); false
+//     para devolver solo la que se ejecutaría
+//
+// Retorna:
+//   - []resolucion: las interpretaciones en orden; vacío si no hay ninguna
+func (sh *Shell) resolverComando(nombre string, todas bool) []resolucion {
+	var resoluciones []resolucion
+
+	// PASO 1: Comandos internos del registro
+	if b, ok := sh.builtins.Buscar(nombre); ok {
+		resoluciones = append(resoluciones, resolucion{tipo: tipoInterno, interno: b})
+		if !todas {
+			return resoluciones
+		}
+	}
+
+	// PASO 2: Programas externos. Con todas se recorre el PATH completo, sin
+	// la tabla hash
+	if todas {
+		for _, ruta := range sh.buscarTodos(nombre) {
+			resoluciones = append(resoluciones, resolucion{tipo: tipoArchivo, ruta: ruta})
+		}
+		return resoluciones
+	}
+	path, _ := sh.variable("PATH")
+	_, enHash := sh.hash.Buscar(path, nombre)
+	if ruta, err := sh.buscarPrograma(nombre); err == nil {
+		resoluciones = append(resoluciones, resolucion{tipo: tipoArchivo, ruta: ruta, enHash: enHash})
+	}
+	return resoluciones
+}
+
+// describirComando escribe cómo se interpreta un nombre, en el formato de type
+func describirComando(w io.Writer, nombre string, r resolucion) {
+	switch {
+	case r.tipo == tipoInterno:
+		fmt.Fprintf(w, "%s es un comando interno de la shell\n", nombre)
+	case r.enHash:
+		fmt.Fprintf(w, "%s está en la tabla hash (%s)\n", nombre, r.ruta)
+	default:
+		fmt.Fprintf(w, "%s es %s\n", nombre, r.ruta)
+	}
+}
+
+// parsearLetras interpreta las opciones de una letra de type y command (ej:
+// "-a -t" o "-at") hasta el primer argumento que no es una opción o "--".
+//
+// Parámetros:
+//   - comando: nombre del comando, para los mensajes de error
+//   - args: argumentos del comando
+//   - validas: letras aceptadas
+//
+// Retorna:
+//   - map[rune]bool: las letras presentes
+//   - []string: los argumentos que siguen a las opciones
+//   - error: si alguna letra no es válida
+func parsearLetras(comando string, args []string, validas string) (map[rune]bool, []string, error) {
+	letras := map[rune]bool{}
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+		opcion := args[0]
+		args = args[1:]
+		if opcion == "--" {
+			break
+		}
+		for _, letra := range opcion[1:] {
+			if !strings.ContainsRune(validas, letra) {
+				return nil, nil, fmt.Errorf("%s: -%c: opción inválida", comando, letra)
+			}
+			letras[letra] = true
+		}
+	}
+	return letras, args, nil
+}
+
+// ejecutarType implementa el comando interno 'type': indica si cada nombre es
+// un comando interno o un programa del PATH (y en ese caso su ruta).
+//
+// Formas soportadas:
+//   - type nombre...: muestra cómo se interpretaría cada nombre
+//   - type -a nombre...: muestra todas las posibilidades: el comando interno
+//     y cada programa del PATH con ese nombre, en orden
+//   - type -t nombre...: muestra solo el tipo: builtin o file
+//   - type -p nombre...: muestra solo la ruta de los que son programas
+//
+// Parámetros:
+//   - sh: shell en la que se buscan los comandos
+//   - args: opciones y nombres a describir
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: nil si se encontraron todos los nombres, error en caso contrario
+//            (con -t o -p, solo el estado 1, sin mensaje)
+func ejecutarType(sh *Shell, args []string, es EntradaSalida) error {
+	letras, nombres, err := parsearLetras("type", args, "atp")
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, nombre := range nombres {
+		resoluciones := sh.resolverComando(nombre, letras['a'])
+		if len(resoluciones) == 0 {
+			if letras['t'] || letras['p'] {
+				errs = append(errs, EstadoSalida(1))
+			} else {
+				errs = append(errs, fmt.Errorf("type: %s: no encontrado", nombre))
+			}
+			continue
+		}
+
+		for _, r := range resoluciones {
+			switch {
+			case letras['t']:
+				fmt.Fprintln(es.Salida, r.tipo)
+			case letras['p']:
+				if r.tipo == tipoArchivo {
+					fmt.Fprintln(es.Salida, r.ruta)
+				}
+			default:
+				describirComando(es.Salida, nombre, r)
+			}
+		}
+	}
+	return unirErrores(errs)
+}
+
+// ejecutarCommand implementa el comando interno 'command': ejecuta un comando
+// sin buscarlo entre los alias ni las funciones, o lo describe.
+//
+// Formas soportadas:
+//   - command nombre [argumentos...]: ejecuta el comando interno o el programa
+//   - command -v nombre...: muestra el nombre de cada comando interno y la
+//     ruta de cada programa
+//   - command -V nombre...: describe cada comando, como type
+//
+// Parámetros:
+//   - sh: shell en la que se busca y ejecuta el comando
+//   - args: opciones, comando y argumentos
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: el resultado del comando ejecutado, o si algún nombre no existe
+func ejecutarCommand(sh *Shell, args []string, es EntradaSalida) error {
+	letras, args, err := parsearLetras("command", args, "vV")
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	// Sin -v ni -V, ejecutar el comando
+	if !letras['v'] && !letras['V'] {
+		if r := sh.resolverComando(args[0], false); len(r) > 0 && r[0].tipo == tipoInterno {
+			return r[0].interno.Ejecutar(sh, args[1:], es)
+		}
+		return sh.ejecutarPrograma(Comando{Nombre: args[0], Args: args[1:]}, es)
+	}
+
+	var errs []error
+	for _, nombre := range args {
+		resoluciones := sh.resolverComando(nombre, false)
+		switch {
+		case len(resoluciones) == 0 && letras['V']:
+			errs = append(errs, fmt.Errorf("command: %s: no encontrado", nombre))
+		case len(resoluciones) == 0:
+			errs = append(errs, EstadoSalida(1))
+		case letras['V']:
+			describirComando(es.Salida, nombre, resoluciones[0])
+		case resoluciones[0].tipo == tipoInterno:
+			fmt.Fprintln(es.Salida, nombre)
+		default:
+			fmt.Fprintln(es.Salida, resoluciones[0].ruta)
+		}
+	}
+	return unirErrores(errs)
+}
+
+// ejecutarBuiltin implementa el comando interno 'builtin': ejecuta un comando
+// interno aunque otra cosa con el mismo nombre tuviera prioridad.
+//
+// Parámetros:
+//   - sh: shell en la que se busca el comando interno
+//   - args: nombre del comando interno y sus argumentos
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: el resultado del comando interno, o si no existe
+func ejecutarBuiltin(sh *Shell, args []string, es EntradaSalida) error {
+	if len(args) == 0 {
+		return nil
+	}
+	b, ok := sh.builtins.Buscar(args[0])
+	if !ok {
+		return fmt.Errorf("builtin: %s: no es un comando interno de la shell", args[0])
+	}
+	return b.Ejecutar(sh, args[1:], es)
+}
+
+// unirErrores combina los errores de un comando que procesa varios nombres.
+// Si todos son solo estados (EstadoSalida), devuelve el último, para que el
+// comando no muestre ningún mensaje.
+func unirErrores(errs []error) error {
+	var mensajes []error
+	for _, err := range errs {
+		if !conMensaje(err) {
+			continue
+		}
+		mensajes = append(mensajes, err)
+	}
+	if len(mensajes) == 0 && len(errs) > 0 {
+		return errs[len(errs)-1]
+	}
+	return errors.Join(mensajes...)
+}
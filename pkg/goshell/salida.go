@@ -0,0 +1,63 @@
+// Módulo salida: Se encarga de la terminación ordenada de la shell
+// En lugar de llamar a os.Exit desde cualquier lugar, el comando exit devuelve
+// un error centinela que hace terminar el bucle REPL (o Run), y antes de salir
+// se ejecutan las funciones de limpieza registradas por los demás módulos
+package goshell
+
+import (
+	"fmt" // Para el texto del error centinela
+)
+
+// SalidaShell es el error centinela que devuelve el comando exit. Atraviesa
+// EjecutarComando hasta el bucle REPL, que deja de leer comandos y retorna
+// el estado indicado.
+type SalidaShell struct {
+	Estado int   // Código de salida con el que debe terminar la shell
+	Causa  error // Error que provocó la salida (ej: con set -e), o nil
+}
+
+// Error implementa la interfaz error
+func (s *SalidaShell) Error() string {
+	return fmt.Sprintf("exit %d", s.Estado)
+}
+
+// alSalir registra una función que se ejecutará cuando la shell termine (por
+// exit, fin de la entrada o SIGHUP). Las funciones se ejecutan en orden inverso
+// al de registro, como los defer de Go: lo último en prepararse es lo primero
+// en deshacerse (ej: trap EXIT, guardar el historial, restaurar la terminal y
+// por último avisar a los trabajos).
+//
+// Parámetros:
+//   - f: función de limpieza; recibe el estado de salida y retorna el estado
+//     definitivo (normalmente el mismo que recibió)
+func (sh *Shell) alSalir(f func(estado int) int) {
+	sh.limpiezaMu.Lock()
+	defer sh.limpiezaMu.Unlock()
+
+	sh.funcionesSalida = append(sh.funcionesSalida, f)
+}
+
+// Finalizar ejecuta todas las funciones de limpieza registradas, una sola vez
+// aunque se llame varias veces. Quien integra la shell debe llamarla cuando
+// termina de usarla, con el estado que devolvió Run, RunFile o EjecutarREPL.
+//
+// Parámetros:
+//   - estado: código de salida solicitado
+//
+// Retorna:
+//   - int: código de salida definitivo con el que terminar el proceso
+func (sh *Shell) Finalizar(estado int) int {
+	sh.limpiezaMu.Lock()
+	if sh.limpiezaEjecutada {
+		sh.limpiezaMu.Unlock()
+		return estado
+	}
+	sh.limpiezaEjecutada = true
+	funciones := sh.funcionesSalida
+	sh.limpiezaMu.Unlock()
+
+	for i := len(funciones) - 1; i >= 0; i-- {
+		estado = funciones[i](estado)
+	}
+	return estado
+}
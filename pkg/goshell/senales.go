@@ -0,0 +1,288 @@
+// Módulo señales: Traduce nombres de señales y contiene el comando interno kill
+// El envío real de señales depende del sistema operativo (ver procesos_*.go)
+package goshell
+
+import (
+	"errors"    // Para combinar los errores de varios destinos de kill
+	"fmt"       // Para mostrar el listado de señales y los errores
+	"io"        // Para escribir el listado en la salida del comando
+	"os"        // Para el tipo os.Signal
+	"os/signal" // Para recibir las señales dirigidas a la shell
+	"sort"      // Para listar las señales en orden numérico
+	"strconv"   // Para interpretar señales y PIDs numéricos
+	"strings"   // Para normalizar los nombres de señales
+	"sync"      // Para proteger las suscripciones compartidas entre shells
+	"syscall"   // Para el tipo syscall.Signal
+)
+
+// suscripciones reparte las señales que recibe el proceso entre las shells
+// que las atienden. Cada señal se registra con signal.Notify en un canal
+// propio mientras al menos una shell la atienda, y al quedar sin shells solo
+// se detiene ese canal: una shell nunca quita ni ignora para todo el proceso
+// las señales que atienden el programa que la integra u otras shells.
+var suscripciones = struct {
+	mu      sync.Mutex
+	canales map[syscall.Signal]chan os.Signal  // Canal registrado para cada señal
+	shells  map[syscall.Signal]map[*Shell]bool // Shells que atienden cada señal
+}{
+	canales: map[syscall.Signal]chan os.Signal{},
+	shells:  map[syscall.Signal]map[*Shell]bool{},
+}
+
+// IniciarManejoSenales configura la respuesta de la shell a las señales que
+// recibe el proceso. Al recibir SIGHUP (por ejemplo, cuando se cierra la
+// terminal) el bucle REPL termina con el estado 128 + 1 y la limpieza de salida
+// reenvía SIGHUP a los trabajos. Solo debe llamarla la shell que representa al
+// proceso (la de main), no las shells integradas en otros programas.
+func (sh *Shell) IniciarManejoSenales() {
+	sh.manejoSenales = true
+	sh.suscribirSenal(syscall.SIGHUP, true)
+}
+
+// suscribirSenal hace que la shell reciba (o deje de recibir) una señal del
+// proceso en su canal de señales. Llamarla dos veces con el mismo valor no
+// tiene efecto.
+//
+// Parámetros:
+//   - senal: señal a atender
+//   - atender: true para recibirla; false para dejar de recibirla
+func (sh *Shell) suscribirSenal(senal syscall.Signal, atender bool) {
+	suscripciones.mu.Lock()
+	defer suscripciones.mu.Unlock()
+
+	shells := suscripciones.shells[senal]
+	if shells[sh] == atender {
+		return
+	}
+	if atender {
+		if shells == nil {
+			shells = map[*Shell]bool{}
+			suscripciones.shells[senal] = shells
+		}
+		shells[sh] = true
+		if len(shells) == 1 {
+			// Primera shell que atiende la señal: registrarla en el proceso
+			canal := make(chan os.Signal, 1)
+			suscripciones.canales[senal] = canal
+			signal.Notify(canal, senal)
+			go repartirSenal(canal)
+		}
+		return
+	}
+
+	delete(shells, sh)
+	if len(shells) == 0 {
+		// Ninguna shell la atiende: detener solo el canal propio, sin tocar
+		// los que haya registrado el programa que integra la shell
+		canal := suscripciones.canales[senal]
+		signal.Stop(canal)
+		close(canal)
+		delete(suscripciones.canales, senal)
+		delete(suscripciones.shells, senal)
+	}
+}
+
+// dejarSenales quita todas las suscripciones de la shell. Se ejecuta al
+// finalizarla (ver NuevaShell).
+func (sh *Shell) dejarSenales() {
+	suscripciones.mu.Lock()
+	var senales []syscall.Signal
+	for senal, shells := range suscripciones.shells {
+		if shells[sh] {
+			senales = append(senales, senal)
+		}
+	}
+	suscripciones.mu.Unlock()
+
+	for _, senal := range senales {
+		sh.suscribirSenal(senal, false)
+	}
+}
+
+// repartirSenal entrega cada señal que llega a un canal registrado a todas
+// las shells que la atienden. Termina cuando el canal se cierra.
+func repartirSenal(canal chan os.Signal) {
+	for s := range canal {
+		senal, ok := s.(syscall.Signal)
+		if !ok {
+			continue
+		}
+		suscripciones.mu.Lock()
+		for sh := range suscripciones.shells[senal] {
+			// Una shell que no atiende sus señales no debe bloquear a las demás
+			select {
+			case sh.senales <- s:
+			default:
+			}
+		}
+		suscripciones.mu.Unlock()
+	}
+}
+
+// parsearSenal interpreta una señal escrita por el usuario. Acepta el número
+// (ej: "15"), el nombre con o sin prefijo SIG y en cualquier combinación de
+// mayúsculas y minúsculas (ej: "TERM", "SIGTERM", "term").
+//
+// Parámetros:
+//   - texto: señal a interpretar
+//
+// Retorna:
+//   - syscall.Signal: la señal correspondiente
+//   - error: si la señal no existe
+func parsearSenal(texto string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(texto); err == nil {
+		// La señal 0 no envía nada, solo comprueba que el proceso exista
+		if n == 0 || nombreCortoSenal(syscall.Signal(n)) != "" {
+			return syscall.Signal(n), nil
+		}
+		return 0, fmt.Errorf("%s: especificación de señal inválida", texto)
+	}
+
+	nombre := strings.TrimPrefix(strings.ToUpper(texto), "SIG")
+	if senal, ok := tablaSenales[nombre]; ok {
+		return senal, nil
+	}
+	return 0, fmt.Errorf("%s: especificación de señal inválida", texto)
+}
+
+// nombreCortoSenal devuelve el nombre de la señal sin el prefijo SIG
+// (ej: "TERM"), o una cadena vacía si la señal no está en la tabla.
+func nombreCortoSenal(senal syscall.Signal) string {
+	for nombre, s := range tablaSenales {
+		if s == senal {
+			return nombre
+		}
+	}
+	return ""
+}
+
+// senalesOrdenadas devuelve todas las señales conocidas ordenadas por número
+func senalesOrdenadas() []syscall.Signal {
+	senales := make([]syscall.Signal, 0, len(tablaSenales))
+	for _, s := range tablaSenales {
+		senales = append(senales, s)
+	}
+	sort.Slice(senales, func(i, j int) bool { return senales[i] < senales[j] })
+	return senales
+}
+
+// ejecutarKill implementa el comando interno 'kill' para enviar señales a
+// trabajos y procesos.
+//
+// Formas soportadas:
+//   - kill %2 / kill 1234: envía SIGTERM al trabajo o proceso
+//   - kill -TERM %build / kill -9 %1 / kill -SIGHUP 1234: señal por nombre o número
+//   - kill -s HUP 1234 / kill -n 1 1234: señal como argumento separado
+//   - kill -l: lista las señales; kill -l 15 / kill -l 143 muestra el nombre
+//
+// Cuando el destino es una especificación de trabajo y el trabajo tiene su
+// propio grupo de procesos, la señal se envía al grupo completo.
+//
+// Parámetros:
+//   - sh: shell cuyos trabajos pueden ser el destino de la señal
+//   - args: slice de argumentos del comando kill
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: nil si todas las señales se enviaron, error en caso contrario
+func ejecutarKill(sh *Shell, args []string, es EntradaSalida) error {
+	if len(args) == 0 {
+		return errors.New("kill: uso: kill [-s señal | -n num | -señal] pid | %trabajo ... o kill -l [señal]")
+	}
+
+	// PASO 1: Determinar la señal a enviar (por defecto SIGTERM)
+	senal := syscall.SIGTERM
+	switch arg := args[0]; {
+	case arg == "-l" || arg == "-L":
+		return listarSenales(args[1:], es.Salida)
+	case arg == "-s" || arg == "-n":
+		if len(args) < 2 {
+			return fmt.Errorf("kill: %s: la opción requiere un argumento", arg)
+		}
+		s, err := parsearSenal(args[1])
+		if err != nil {
+			return fmt.Errorf("kill: %v", err)
+		}
+		senal = s
+		args = args[2:]
+	case arg == "--":
+		args = args[1:]
+	case strings.HasPrefix(arg, "-") && len(arg) > 1:
+		s, err := parsearSenal(arg[1:])
+		if err != nil {
+			return fmt.Errorf("kill: %v", err)
+		}
+		senal = s
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		return errors.New("kill: falta el PID o la especificación de trabajo")
+	}
+
+	// PASO 2: Enviar la señal a cada destino, acumulando los errores
+	var errs []error
+	for _, destino := range args {
+		if err := sh.enviarSenalDestino(destino, senal); err != nil {
+			errs = append(errs, fmt.Errorf("kill: %v", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// enviarSenalDestino envía una señal a un destino de kill: una especificación
+// de trabajo (%N, %texto, ...) o un PID cualquiera.
+func (sh *Shell) enviarSenalDestino(destino string, senal syscall.Signal) error {
+	if strings.HasPrefix(destino, "%") {
+		t, err := sh.trabajos.Buscar(destino)
+		if err != nil {
+			return err
+		}
+		if err := enviarSenal(t.PID, t.Grupo, senal); err != nil {
+			return fmt.Errorf("%s: %v", destino, err)
+		}
+		return nil
+	}
+
+	pid, err := strconv.Atoi(destino)
+	if err != nil {
+		return fmt.Errorf("%s: los argumentos deben ser PIDs o especificaciones de trabajo", destino)
+	}
+	if err := enviarSenal(pid, false, senal); err != nil {
+		return fmt.Errorf("(%d) - %v", pid, err)
+	}
+	return nil
+}
+
+// listarSenales implementa "kill -l". Sin argumentos muestra todas las señales
+// con su número; con argumentos traduce cada número (o estado de salida mayor
+// que 128) a su nombre y cada nombre a su número.
+func listarSenales(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		for _, s := range senalesOrdenadas() {
+			fmt.Fprintf(w, "%2d) SIG%s\n", int(s), nombreCortoSenal(s))
+		}
+		return nil
+	}
+
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil {
+			// Un estado de salida 128+N corresponde a la señal N
+			if n > 128 {
+				n -= 128
+			}
+			nombre := nombreCortoSenal(syscall.Signal(n))
+			if nombre == "" {
+				return fmt.Errorf("kill: %s: especificación de señal inválida", arg)
+			}
+			fmt.Fprintln(w, nombre)
+			continue
+		}
+		s, err := parsearSenal(arg)
+		if err != nil {
+			return fmt.Errorf("kill: %v", err)
+		}
+		fmt.Fprintln(w, int(s))
+	}
+	return nil
+}
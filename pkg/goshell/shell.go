@@ -0,0 +1,361 @@
+// Paquete goshell: Intérprete de GoShell listo para integrarse en otros programas
+// La estructura Shell contiene todo el estado de una sesión (opciones, trabajos,
+// trampas, $?) y los flujos y el entorno con los que se ejecutan los comandos,
+// de modo que varias shells pueden convivir sin tocar el estado del proceso
+package goshell
+
+import (
+	"context"       // Para cancelar la ejecución de Run y RunFile
+	"errors"        // Para reconocer el error centinela de exit
+	"fmt"           // Para mostrar los errores de los comandos
+	"io"            // Para los flujos estándar configurables
+	"maps"          // Para copiar las trampas en una subshell
+	"os"            // Para los valores por defecto y leer scripts
+	"path/filepath" // Para reconocer la ruta lógica de $PWD
+	"slices"        // Para copiar el entorno y la pila en una subshell
+	"strings"       // Para dividir el código fuente en líneas
+	"sync"          // Para proteger la limpieza de salida y el prompt
+	"time"          // Para la espera entre SIGTERM y SIGKILL al cancelar
+)
+
+// Shell agrupa todo el estado de una sesión de la shell.
+//
+// Los campos exportados pueden modificarse antes de ejecutar comandos:
+//
+//	sh := goshell.NuevaShell()
+//	sh.Stdout = &salida
+//	sh.Env = []string{"PATH=/usr/bin", "NOMBRE=mundo"}
+//	estado, err := sh.Run(ctx, "echo hola $NOMBRE | tr a-z A-Z")
+type Shell struct {
+	Stdin  io.Reader // Entrada estándar de los comandos
+	Stdout io.Writer // Salida estándar de los comandos, el prompt y los avisos
+	Stderr io.Writer // Error estándar de los comandos y mensajes de error
+
+	// Env es el entorno de la shell en formato "CLAVE=valor". Lo usan la
+	// expansión de variables, la búsqueda en el PATH y los comandos externos
+	Env []string
+
+	// Dir es el directorio de trabajo de la shell. Lo cambia cd (nunca se
+	// cambia el del proceso), los comandos externos se ejecutan en él y las
+	// rutas relativas de las redirecciones y los comandos internos se
+	// resuelven desde él
+	Dir string
+
+	// EsperaCancelacion es el tiempo que tienen los procesos externos para
+	// terminar tras recibir SIGTERM, cuando se cancela el contexto de Run o
+	// vence un timeout. Pasado ese tiempo reciben SIGKILL
+	EsperaCancelacion time.Duration
+
+	builtins    *RegistroBuiltins // Comandos internos disponibles
+	trabajos    *TablaTrabajos    // Trabajos en segundo plano
+	hash        *TablaHash        // Rutas de los programas ya encontrados
+	historial   *Historial        // Líneas ejecutadas en el REPL (ver historial.go)
+	opciones    Opciones          // Opciones modificables con set
+	interactiva bool              // true si la shell lee comandos de una terminal
+	ctx         context.Context   // Contexto de la llamada a Run en curso (nil fuera de Run)
+
+	// sustitucion es la última sustitución s/viejo/nuevo/ (o ^viejo^nuevo)
+	// de la expansión del historial, que repite el modificador :& (ver
+	// expansion_historial.go)
+	sustitucion [2]string
+
+	// pila contiene los directorios guardados con pushd, sin el actual: dirs
+	// muestra Dir seguido de la pila (ver pila.go)
+	pila []string
+
+	ultimoEstado          int  // Código de salida del último comando ($?)
+	ultimoPIDSegundoPlano int  // PID del último trabajo en segundo plano ($!)
+	avisoSalidaMostrado   bool // true si exit ya advirtió que hay trabajos en ejecución
+
+	// trampas asocia el nombre de cada señal (sin prefijo SIG) o pseudo-señal
+	// con el comando a ejecutar. Un comando vacío significa que la señal se
+	// ignora. Solo la modifica y la lee el bucle principal, sin mutex.
+	trampas map[string]string
+
+	// enTrampa evita que los comandos de un manejador disparen a su vez las
+	// trampas DEBUG y ERR
+	enTrampa bool
+
+	// senales recibe las señales dirigidas a la shell que esta atiende por su
+	// cuenta: SIGHUP y las señales con una trampa definida. El bucle REPL las
+	// lee entre comandos (ver atenderSenal en trampas.go)
+	senales chan os.Signal
+
+	// manejoSenales es true si la shell representa al proceso y atiende
+	// SIGHUP aunque no tenga trampa (ver IniciarManejoSenales)
+	manejoSenales bool
+
+	// Funciones de limpieza que se ejecutan al terminar la shell (ver salida.go)
+	limpiezaMu        sync.Mutex
+	funcionesSalida   []func(estado int) int
+	limpiezaEjecutada bool
+
+	// Estado del prompt compartido con las goroutines de segundo plano. Permite
+	// volver a dibujarlo después de una notificación inmediata (set -b)
+	salidaMu       sync.Mutex // Evita que prompt y notificaciones se mezclen
+	promptActual   string     // Último prompt mostrado
+	esperandoLinea bool       // true mientras el REPL espera la entrada del usuario
+
+	// editor lee las líneas del REPL (nil fuera de EjecutarREPL)
+	editor *editorLinea
+}
+
+// NuevaShell crea una shell conectada a los flujos estándar, al entorno y al
+// directorio actual del proceso, con todas las opciones desactivadas, sin
+// trabajos ni trampas, y con los comandos internos estándar registrados.
+//
+// Al finalizar (ver Finalizar) la shell ejecuta la trampa EXIT y envía SIGHUP
+// a sus trabajos en segundo plano.
+//
+// Retorna:
+//   - *Shell: la shell lista para ejecutar comandos
+func NuevaShell() *Shell {
+	sh := &Shell{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Env:    os.Environ(),
+		Dir:    directorioProceso(),
+
+		EsperaCancelacion: esperaCancelacionPredeterminada,
+
+		builtins:  NuevoRegistroBuiltins(),
+		trabajos:  &TablaTrabajos{},
+		hash:      &TablaHash{},
+		historial: &Historial{},
+		trampas:   map[string]string{},
+		senales:   make(chan os.Signal, 16),
+	}
+	for _, b := range comandosInternos {
+		sh.builtins.Registrar(b)
+	}
+
+	// Indexar los programas del PATH mientras la shell arranca
+	sh.indexarPath()
+
+	// Al terminar, avisar a los trabajos en segundo plano con SIGHUP y dejar
+	// de recibir señales. Se registra primero para que sea lo último en
+	// ejecutarse
+	sh.alSalir(func(estado int) int {
+		sh.trabajos.EnviarHup()
+		sh.dejarSenales()
+		return estado
+	})
+
+	// La trampa EXIT se ejecuta antes que el resto de la limpieza
+	sh.alSalir(sh.ejecutarTrampaSalida)
+	return sh
+}
+
+// Builtins devuelve el registro de comandos internos de la shell, para
+// agregar comandos propios con Registrar
+func (sh *Shell) Builtins() *RegistroBuiltins {
+	return sh.builtins
+}
+
+// subshell crea una copia de la shell para ejecutar un comando interno como
+// etapa de una tubería, igual que bash ejecuta cada etapa en un proceso hijo.
+// El directorio, el entorno, las opciones, las trampas, la pila de
+// directorios, la tabla hash y el historial son copias: lo que cambie el
+// comando (cd, set, trap, pushd, hash -r...) no afecta a la shell original, y
+// las etapas que corren a la vez no comparten estado sin protección. Los
+// comandos internos registrados y la tabla de trabajos se comparten, para
+// que "kill %1 | cat" llegue a los trabajos de la shell.
+//
+// Retorna:
+//   - *Shell: la subshell, que no atiende señales ni tiene editor de línea
+func (sh *Shell) subshell() *Shell {
+	sub := &Shell{
+		Stdin:  sh.Stdin,
+		Stdout: sh.Stdout,
+		Stderr: sh.Stderr,
+		Env:    slices.Clone(sh.Env),
+		Dir:    sh.Dir,
+
+		EsperaCancelacion: sh.EsperaCancelacion,
+
+		builtins:    sh.builtins,
+		trabajos:    sh.trabajos,
+		hash:        sh.hash.clonar(),
+		historial:   sh.historial.clonar(),
+		interactiva: sh.interactiva,
+		ctx:         sh.ctx,
+		sustitucion: sh.sustitucion,
+		pila:        slices.Clone(sh.pila),
+
+		ultimoEstado:          sh.ultimoEstado,
+		ultimoPIDSegundoPlano: sh.ultimoPIDSegundoPlano,
+
+		trampas:      maps.Clone(sh.trampas),
+		enTrampa:     sh.enTrampa,
+		senales:      make(chan os.Signal, 16),
+		promptActual: sh.promptActual,
+	}
+	for i := range sh.opciones {
+		sub.opciones[i].Store(sh.opciones[i].Load())
+	}
+	return sub
+}
+
+// Run ejecuta código de la shell línea por línea, como si se escribiera en el
+// prompt. Los errores de cada comando se muestran en Stderr y no detienen la
+// ejecución (salvo con set -e); exit termina la ejecución del resto del código.
+// Las líneas que empiezan con "#" son comentarios.
+//
+// Si el contexto se cancela, los procesos externos en primer plano reciben
+// SIGTERM y, pasado EsperaCancelacion, SIGKILL; los trabajos en segundo plano
+// no se ven afectados.
+//
+// Parámetros:
+//   - ctx: contexto; si se cancela no se ejecutan más líneas
+//   - src: código a ejecutar, una orden por línea
+//
+// Retorna:
+//   - int: estado de salida ($? del último comando, o el indicado por exit)
+//   - error: el error del contexto si la ejecución se canceló, nil en otro caso
+func (sh *Shell) Run(ctx context.Context, src string) (int, error) {
+	anterior := sh.ctx
+	sh.ctx = ctx
+	defer func() { sh.ctx = anterior }()
+
+	for _, linea := range strings.Split(src, "\n") {
+		if err := ctx.Err(); err != nil {
+			return sh.ultimoEstado, err
+		}
+		if strings.HasPrefix(strings.TrimSpace(linea), "#") {
+			continue
+		}
+
+		err := sh.EjecutarLinea(linea)
+		if salida, termina := sh.informarError(err); termina {
+			return salida.Estado, nil
+		}
+
+		var salida *SalidaShell
+		if err := sh.atenderSenalesPendientes(); errors.As(err, &salida) {
+			return salida.Estado, nil
+		}
+	}
+	return sh.ultimoEstado, ctx.Err()
+}
+
+// RunFile ejecuta un script de la shell con Run; una ruta relativa se busca
+// desde el directorio de la shell. Al terminar se ejecuta la trampa RETURN,
+// igual que al terminar un script leído con "source" en bash.
+//
+// Parámetros:
+//   - ctx: contexto; si se cancela no se ejecutan más líneas
+//   - ruta: ruta del script
+//
+// Retorna:
+//   - int: estado de salida del script
+//   - error: si el script no pudo leerse o la ejecución se canceló
+func (sh *Shell) RunFile(ctx context.Context, ruta string) (int, error) {
+	contenido, err := os.ReadFile(sh.rutaAbsoluta(ruta))
+	if err != nil {
+		return 127, err
+	}
+
+	estado, err := sh.Run(ctx, string(contenido))
+	if _, errTrampa := sh.ejecutarTrampa(trampaRetorno); errTrampa != nil {
+		var salida *SalidaShell
+		if errors.As(errTrampa, &salida) {
+			estado = salida.Estado
+		}
+	}
+	return estado, err
+}
+
+// informarError muestra en Stderr el error de una línea ejecutada.
+//
+// Retorna:
+//   - *SalidaShell: el error centinela si la línea terminó la shell
+//   - bool: true si la shell debe terminar
+func (sh *Shell) informarError(err error) (*SalidaShell, bool) {
+	// El comando exit devuelve un error centinela: terminar la ejecución.
+	// Con set -e el error que provocó la salida se muestra antes de salir
+	var salida *SalidaShell
+	if errors.As(err, &salida) {
+		if conMensaje(salida.Causa) {
+			fmt.Fprintln(sh.Stderr, "Error al ejecutar el comando:", salida.Causa)
+		}
+		return salida, true
+	}
+
+	// Mostrar el error sin terminar la shell
+	if conMensaje(err) {
+		fmt.Fprintln(sh.Stderr, "Error al ejecutar el comando:", err)
+	}
+	return nil, false
+}
+
+// conMensaje indica si un error debe mostrarse. Un EstadoSalida solo fija $?:
+// el comando interno que lo devolvió ya informó lo que tuviera que informar.
+func conMensaje(err error) bool {
+	var estado EstadoSalida
+	return err != nil && !errors.As(err, &estado)
+}
+
+// Estado devuelve el código de salida del último comando ejecutado ($?)
+func (sh *Shell) Estado() int {
+	return sh.ultimoEstado
+}
+
+// estandar devuelve los flujos estándar de la shell, que heredan los comandos
+// que no tienen tuberías ni redirecciones
+func (sh *Shell) estandar() EntradaSalida {
+	return EntradaSalida{Entrada: sh.Stdin, Salida: sh.Stdout, Error: sh.Stderr, Contexto: sh.ctx}
+}
+
+// esTerminal indica si un flujo de entrada es una terminal
+func esTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// directorioProceso devuelve el directorio actual del proceso, o una cadena
+// vacía si no puede obtenerse (los comandos usarán entonces el del proceso).
+// Igual que bash, si $PWD es una ruta lógica (con enlaces simbólicos) que
+// lleva al mismo directorio, se usa esa ruta.
+func directorioProceso() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	if pwd := os.Getenv("PWD"); filepath.IsAbs(pwd) && pwd != dir {
+		infoPwd, err1 := os.Stat(pwd)
+		infoDir, err2 := os.Stat(dir)
+		if err1 == nil && err2 == nil && os.SameFile(infoPwd, infoDir) {
+			return filepath.Clean(pwd)
+		}
+	}
+	return dir
+}
+
+// variable devuelve el valor de una variable del entorno de la shell. Si
+// aparece varias veces en Env, vale la última, igual que en os/exec.
+func (sh *Shell) variable(nombre string) (string, bool) {
+	for i := len(sh.Env) - 1; i >= 0; i-- {
+		if clave, valor, ok := strings.Cut(sh.Env[i], "="); ok && clave == nombre {
+			return valor, true
+		}
+	}
+	return "", false
+}
+
+// definirVariable da un valor a una variable del entorno de la shell. Se
+// construye un Env nuevo, sin las definiciones anteriores de la variable, para
+// no modificar el slice que haya asignado quien integra la shell.
+func (sh *Shell) definirVariable(nombre, valor string) {
+	env := make([]string, 0, len(sh.Env)+1)
+	for _, v := range sh.Env {
+		if clave, _, _ := strings.Cut(v, "="); clave != nombre {
+			env = append(env, v)
+		}
+	}
+	sh.Env = append(env, nombre+"="+valor)
+}
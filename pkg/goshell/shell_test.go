@@ -0,0 +1,1867 @@
+// Módulo de testing: Contiene pruebas unitarias para validar el funcionamiento
+// correcto de los componentes principales de la shell
+package goshell
+
+import (
+	"bufio"        // Para alimentar el editor de línea con teclas
+	"context"      // Para ejecutar código con Run y RunFile
+	"errors"       // Para reconocer el error centinela de exit
+	"io"           // Para descartar los mensajes de los comandos internos
+	"os"           // Para operaciones del sistema operativo en tests
+	"os/exec"      // Para lanzar procesos reales en las pruebas de trabajos
+	"os/signal"    // Para atender señales como un programa que integra la shell
+	"path/filepath" // Para manipulación de rutas de archivos
+	"slices"       // Para comparar a qué destinos llegó una señal
+	"strconv"      // Para construir especificaciones de trabajo
+	"strings"      // Para capturar la salida de las notificaciones
+	"syscall"      // Para comparar señales en las pruebas de kill
+	"testing"      // Framework de testing estándar de Go
+	"time"         // Para dar tiempo a que lleguen las señales
+)
+
+// TestAnalizarEntrada prueba la función de parsing de la entrada del usuario.
+// Verifica que la función AnalizarEntrada procese correctamente diferentes tipos
+// de comandos y detecte apropiadamente la ejecución en segundo plano.
+//
+// Casos de prueba incluidos:
+//   - Comando con argumentos múltiples
+//   - Comando con argumentos que contienen espacios/comillas
+//   - Comando con sufijo & (segundo plano)
+//   - Entrada vacía
+//   - Entrada con solo espacios
+func TestAnalizarEntrada(t *testing.T) {
+	// Definir tabla de casos de prueba
+	// Cada caso contiene: entrada, comando esperado, argumentos esperados, flag segundo plano esperado
+	tests := []struct {
+		linea           string   // Línea de entrada a procesar
+		comandoExp      string   // Comando esperado después del parsing
+		argsExp         []string // Argumentos esperados después del parsing
+		segundoPlanoExp bool     // Flag de segundo plano esperado
+	}{
+		// Caso 1: Comando típico con argumentos múltiples
+		{"ls -l /tmp", "ls", []string{"-l", "/tmp"}, false},
+		
+		// Caso 2: Comando con argumentos que incluyen comillas
+		// Nota: strings.Fields no maneja comillas como shell real,
+		// divide por espacios independientemente de las comillas
+		{"echo 'hola mundo'", "echo", []string{"'hola", "mundo'"}, false},
+		
+		// Caso 3: Comando con sufijo & para ejecución en segundo plano
+		{"sleep 5 &", "sleep", []string{"5"}, true},
+		
+		// Caso 4: Entrada completamente vacía
+		{"", "", nil, false},
+		
+		// Caso 5: Entrada con solo espacios en blanco
+		{"   ", "", nil, false},
+	}
+
+	// Iterar sobre cada caso de prueba
+	for _, tt := range tests {
+		// Ejecutar la función bajo prueba
+		comando, args, segundoPlano := AnalizarEntrada(tt.linea)
+
+		// VERIFICACIÓN 1: Comando obtenido vs esperado
+		if comando != tt.comandoExp {
+			t.Errorf("Comando esperado: %q, obtenido: %q", tt.comandoExp, comando)
+		}
+
+		// VERIFICACIÓN 2: Argumentos obtenidos vs esperados
+		// Usar función auxiliar equal para comparar slices
+		if !equal(args, tt.argsExp) {
+			t.Errorf("Argumentos esperados: %v, obtenidos: %v", tt.argsExp, args)
+		}
+
+		// VERIFICACIÓN 3: Flag de segundo plano obtenido vs esperado
+		if segundoPlano != tt.segundoPlanoExp {
+			t.Errorf("Segundo plano esperado: %v, obtenido: %v", tt.segundoPlanoExp, segundoPlano)
+		}
+	}
+}
+
+// TestEjecutarCd prueba la funcionalidad del comando interno 'cd'.
+// Verifica que el comando cd cambie efectivamente el directorio de trabajo
+// de la shell sin modificar el del proceso.
+//
+// El test:
+//   1. Guarda el directorio actual del proceso
+//   2. Crea un directorio temporal para las pruebas
+//   3. Ejecuta el comando cd hacia el directorio temporal
+//   4. Verifica que el directorio de la shell haya cambiado correctamente
+//   5. Verifica que el directorio del proceso siga siendo el mismo
+func TestEjecutarCd(t *testing.T) {
+	// PASO 1: Guardar el directorio actual del proceso
+	dirActual, _ := os.Getwd()
+
+	// PASO 2: Crear un directorio temporal para la prueba
+	// t.TempDir() crea un directorio temporal que se limpia automáticamente
+	// al final del test
+	tempDir := t.TempDir()
+
+	// PASO 3: Ejecutar el comando cd con el directorio temporal
+	sh := NuevaShell()
+	if err := ejecutarCd(sh, []string{tempDir}, EntradaSalida{}); err != nil {
+		// Si hay error, fallar inmediatamente el test
+		t.Fatalf("Error al cambiar al directorio temporal: %v", err)
+	}
+
+	// PASO 4: Verificar que el directorio de la shell efectivamente cambió
+	// Normalizar las rutas para resolver enlaces simbólicos
+	// Esto es necesario porque algunos sistemas operativos usan enlaces simbólicos
+	// en rutas temporales (ej: /tmp -> /private/tmp en macOS)
+	tempDirEval, _ := filepath.EvalSymlinks(tempDir)
+	dirDespuesEval, _ := filepath.EvalSymlinks(sh.Dir)
+
+	// Comparar las rutas normalizadas
+	if dirDespuesEval != tempDirEval {
+		t.Errorf("Directorio esperado: %q, obtenido: %q", tempDirEval, dirDespuesEval)
+	}
+
+	// PASO 5: El directorio del proceso no debe cambiar
+	if dirProceso, _ := os.Getwd(); dirProceso != dirActual {
+		t.Errorf("cd cambió el directorio del proceso: %q", dirProceso)
+	}
+
+	// Un destino que no es un directorio produce un error
+	if err := ejecutarCd(sh, []string{"no-existe"}, EntradaSalida{}); err == nil {
+		t.Error("Se esperaba un error al cambiar a un directorio inexistente")
+	}
+}
+
+// equal es una función auxiliar que compara dos slices de strings para igualdad.
+// Go no tiene una función built-in para comparar slices, por lo que implementamos
+// nuestra propia función de comparación.
+//
+// Parámetros:
+//   - a, b: slices de strings a comparar
+//
+// Retorna:
+//   - bool: true si los slices son idénticos (mismo tamaño y mismos elementos
+//           en el mismo orden), false en caso contrario
+//
+// Algoritmo:
+//   1. Verificar que tengan la misma longitud
+//   2. Comparar elemento por elemento en el mismo índice
+func equal(a, b []string) bool {
+	// Si tienen longitudes diferentes, no pueden ser iguales
+	if len(a) != len(b) {
+		return false
+	}
+	
+	// Comparar cada elemento en la misma posición
+	for i, v := range a {
+		if v != b[i] {
+			return false // Encontramos una diferencia
+		}
+	}
+	
+	// Si llegamos aquí, todos los elementos son iguales
+	return true
+}
+
+// TestNotificarTrabajos prueba el formato de las notificaciones de trabajos en
+// segundo plano y que los trabajos notificados se eliminen de la tabla.
+//
+// Se usan procesos reales ("true" y "sh -c 'exit 3'") para obtener estados
+// de salida auténticos a través de cmd.Wait().
+func TestNotificarTrabajos(t *testing.T) {
+	tabla := &TablaTrabajos{}
+
+	// PASO 1: Lanzar dos procesos y registrarlos como trabajos
+	cmdOk := exec.Command("true")
+	cmdFallo := exec.Command("sh", "-c", "exit 3")
+	if err := cmdOk.Start(); err != nil {
+		t.Skipf("No se pudo ejecutar 'true': %v", err)
+	}
+	if err := cmdFallo.Start(); err != nil {
+		t.Skipf("No se pudo ejecutar 'sh': %v", err)
+	}
+	trabajoOk := tabla.Agregar(cmdOk.Process.Pid, false, "sleep 5")
+	trabajoFallo := tabla.Agregar(cmdFallo.Process.Pid, false, "make")
+
+	// PASO 2: Esperar ambos procesos y marcarlos como terminados
+	tabla.Finalizar(trabajoOk, cmdOk.Wait())
+	tabla.Finalizar(trabajoFallo, cmdFallo.Wait())
+
+	// PASO 3: Verificar el texto de las notificaciones
+	var salida strings.Builder
+	if n := tabla.NotificarTerminados(&salida); n != 2 {
+		t.Errorf("Notificaciones esperadas: 2, obtenidas: %d", n)
+	}
+	esperado := "[1]- Done  sleep 5\n[2]+ Exit 3  make\n"
+	if salida.String() != esperado {
+		t.Errorf("Salida esperada: %q, obtenida: %q", esperado, salida.String())
+	}
+
+	// PASO 4: Los trabajos ya notificados no se vuelven a informar
+	salida.Reset()
+	if n := tabla.NotificarTerminados(&salida); n != 0 {
+		t.Errorf("No se esperaban notificaciones, obtenidas: %d (%q)", n, salida.String())
+	}
+
+	// PASO 5: La numeración vuelve a empezar cuando la tabla queda vacía
+	if nuevo := tabla.Agregar(1, false, "ls"); nuevo.ID != 1 {
+		t.Errorf("Número de trabajo esperado: 1, obtenido: %d", nuevo.ID)
+	}
+}
+
+// TestEjecutarSet verifica que el comando interno set active y desactive
+// opciones tanto por letra como por nombre.
+func TestEjecutarSet(t *testing.T) {
+	sh := NuevaShell()
+	var es EntradaSalida
+
+	casos := []struct {
+		args     []string // Argumentos del comando set
+		esperado bool     // Estado esperado de la opción notify
+	}{
+		{[]string{"-b"}, true},
+		{[]string{"+b"}, false},
+		{[]string{"-o", "notify"}, true},
+		{[]string{"+o", "notify"}, false},
+	}
+
+	for _, c := range casos {
+		if err := ejecutarSet(sh, c.args, es); err != nil {
+			t.Fatalf("set %v: error inesperado: %v", c.args, err)
+		}
+		if sh.opciones[optNotify].Load() != c.esperado {
+			t.Errorf("set %v: notify esperado %v, obtenido %v", c.args, c.esperado, sh.opciones[optNotify].Load())
+		}
+	}
+
+	// Las opciones desconocidas deben producir un error
+	if err := ejecutarSet(sh, []string{"-Z"}, es); err == nil {
+		t.Error("Se esperaba un error para 'set -Z'")
+	}
+}
+
+// TestEjecutarWait prueba el comando interno wait con especificaciones de
+// trabajo, PIDs y la forma "wait -n". Lanza trabajos reales en segundo plano
+// a través de EjecutarComando.
+func TestEjecutarWait(t *testing.T) {
+	sh := NuevaShell()
+	var es EntradaSalida
+
+	// PASO 1: Lanzar un trabajo lento y otro que termina de inmediato
+	if err := sh.EjecutarComando("sh", []string{"-c", "sleep 0.3; exit 4"}, true); err != nil {
+		t.Skipf("No se pudo lanzar el trabajo en segundo plano: %v", err)
+	}
+	if err := sh.EjecutarComando("sh", []string{"-c", "exit 3"}, true); err != nil {
+		t.Skipf("No se pudo lanzar el trabajo en segundo plano: %v", err)
+	}
+	trabajos := sh.trabajos.Todos()
+	lento := trabajos[len(trabajos)-2]
+
+	// PASO 2: wait -n debe devolver el estado del primero en terminar
+	if err := ejecutarWait(sh, []string{"-n"}, es); err != EstadoSalida(3) {
+		t.Errorf("wait -n: se esperaba exit status 3, obtenido: %v", err)
+	}
+
+	// PASO 3: wait %N debe devolver el estado del trabajo indicado
+	espec := "%" + strconv.Itoa(lento.ID)
+	if err := ejecutarWait(sh, []string{espec}, es); err != EstadoSalida(4) {
+		t.Errorf("wait %s: se esperaba exit status 4, obtenido: %v", espec, err)
+	}
+
+	// PASO 4: un PID que no es hijo de la shell produce un error
+	if err := ejecutarWait(sh, []string{strconv.Itoa(lento.PID)}, es); err == nil {
+		t.Error("Se esperaba un error al esperar un PID ya retirado")
+	}
+
+	// PASO 5: wait -n sin trabajos pendientes retorna 127
+	if err := ejecutarWait(sh, []string{"-n"}, es); err != EstadoSalida(127) {
+		t.Errorf("wait -n sin trabajos: se esperaba exit status 127, obtenido: %v", err)
+	}
+}
+
+// TestEntradaSegundoPlano prueba que un trabajo en segundo plano lea de
+// /dev/null en lugar de la entrada de la shell, salvo con set -o bgstdin.
+func TestEntradaSegundoPlano(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skipf("No se encontró 'cat': %v", err)
+	}
+	// La salida va a un archivo, que los procesos escriben directamente
+	salida, err := os.Create(filepath.Join(t.TempDir(), "salida"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer salida.Close()
+	leerSalida := func() string {
+		contenido, _ := os.ReadFile(salida.Name())
+		return string(contenido)
+	}
+	sh := NuevaShell()
+	sh.Stdout = salida
+	sh.Stderr = io.Discard
+	ctx := context.Background()
+
+	// PASO 1: cat & termina al leer el fin de /dev/null, aunque la tubería
+	// de la shell tenga datos y siga abierta
+	lectura, escritura, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lectura.Close()
+	sh.Stdin = lectura
+	escritura.WriteString("datos\n")
+	hecho := make(chan int, 1)
+	go func() {
+		estado, _ := sh.Run(ctx, "cat &\nwait")
+		hecho <- estado
+	}()
+	select {
+	case estado := <-hecho:
+		if estado != 0 || strings.Contains(leerSalida(), "datos") {
+			t.Errorf("cat &: estado %d, salida %q", estado, leerSalida())
+		}
+	case <-time.After(5 * time.Second):
+		escritura.Close()
+		t.Fatal("cat & leyó la entrada de la shell")
+	}
+	escritura.Close()
+	if resto, _ := io.ReadAll(lectura); string(resto) != "datos\n" {
+		t.Errorf("La tubería perdió datos: quedó %q", resto)
+	}
+
+	// PASO 2: Con bgstdin el trabajo conserva la entrada de la shell
+	lectura, escritura, err = os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lectura.Close()
+	sh.Stdin = lectura
+	escritura.WriteString("datos\n")
+	escritura.Close()
+	if estado, _ := sh.Run(ctx, "set -o bgstdin\ncat &\nwait"); estado != 0 || !strings.Contains(leerSalida(), "datos\n") {
+		t.Errorf("bgstdin: estado %d, salida %q", estado, leerSalida())
+	}
+}
+
+// TestEjecutarKill prueba el comando interno kill: la interpretación de
+// señales por nombre y número, y el envío a un trabajo por especificación.
+func TestEjecutarKill(t *testing.T) {
+	sh := NuevaShell()
+	var es EntradaSalida
+
+	// PASO 1: Interpretación de señales
+	casos := []struct {
+		texto    string         // Señal escrita por el usuario
+		esperada syscall.Signal // Señal esperada
+	}{
+		{"TERM", syscall.SIGTERM},
+		{"SIGHUP", syscall.SIGHUP},
+		{"kill", syscall.SIGKILL},
+		{"2", syscall.SIGINT},
+	}
+	for _, c := range casos {
+		senal, err := parsearSenal(c.texto)
+		if err != nil || senal != c.esperada {
+			t.Errorf("parsearSenal(%q) = %v, %v; esperado %v", c.texto, senal, err, c.esperada)
+		}
+	}
+	if _, err := parsearSenal("NOEXISTE"); err == nil {
+		t.Error("Se esperaba un error para una señal inexistente")
+	}
+
+	// PASO 2: Enviar SIGTERM a un trabajo real por especificación de texto
+	if err := sh.EjecutarComando("sleep", []string{"30"}, true); err != nil {
+		t.Skipf("No se pudo lanzar el trabajo en segundo plano: %v", err)
+	}
+	if err := ejecutarKill(sh, []string{"-s", "TERM", "%sleep"}, es); err != nil {
+		t.Fatalf("kill -s TERM %%sleep: error inesperado: %v", err)
+	}
+
+	// PASO 3: El trabajo debe terminar por la señal (estado 128 + 15)
+	if err := ejecutarWait(sh, []string{"-n"}, es); err != EstadoSalida(128+int(syscall.SIGTERM)) {
+		t.Errorf("Se esperaba exit status %d, obtenido: %v", 128+int(syscall.SIGTERM), err)
+	}
+}
+
+// TestEjecutarDisown prueba que "disown -h" excluya un trabajo del SIGHUP
+// enviado al salir, que "disown" lo retire de la tabla y que exit advierta
+// sobre los trabajos en ejecución.
+func TestEjecutarDisown(t *testing.T) {
+	sh := NuevaShell()
+	var es EntradaSalida
+
+	// PASO 1: Lanzar un trabajo y excluirlo del SIGHUP
+	if err := sh.EjecutarComando("sleep", []string{"30"}, true); err != nil {
+		t.Skipf("No se pudo lanzar el trabajo en segundo plano: %v", err)
+	}
+	trabajo, err := sh.trabajos.Buscar("%+")
+	if err != nil {
+		t.Fatalf("No se encontró el trabajo actual: %v", err)
+	}
+	if err := ejecutarDisown(sh, []string{"-h"}, es); err != nil {
+		t.Fatalf("disown -h: error inesperado: %v", err)
+	}
+
+	// PASO 2: exit debe advertir en lugar de salir mientras haya trabajos
+	if err := sh.EjecutarComando("exit", nil, false); err == nil {
+		t.Error("Se esperaba una advertencia de exit por trabajos en ejecución")
+	}
+
+	// PASO 3: El SIGHUP de salida no debe terminar el trabajo excluido
+	sh.trabajos.EnviarHup()
+	time.Sleep(100 * time.Millisecond)
+	if sh.trabajos.Terminado(trabajo) {
+		t.Error("El trabajo excluido con disown -h terminó al recibir el SIGHUP de salida")
+	}
+
+	// PASO 4: disown retira el trabajo de la tabla
+	if err := ejecutarDisown(sh, []string{"%" + strconv.Itoa(trabajo.ID)}, es); err != nil {
+		t.Fatalf("disown: error inesperado: %v", err)
+	}
+	if _, err := sh.trabajos.Buscar(strconv.Itoa(trabajo.PID)); err == nil {
+		t.Error("El trabajo desligado sigue en la tabla")
+	}
+
+	// Terminar el proceso, que ya no pertenece a la tabla
+	ejecutarKill(sh, []string{strconv.Itoa(trabajo.PID)}, es)
+}
+
+// TestEjecutarExit verifica que exit devuelva el error centinela con el estado
+// correcto en lugar de terminar el proceso.
+func TestEjecutarExit(t *testing.T) {
+	sh := NuevaShell()
+	sh.ultimoEstado = 5
+	es := EntradaSalida{Error: io.Discard}
+
+	casos := []struct {
+		args     []string // Argumentos del comando exit
+		esperado int      // Estado de salida esperado
+	}{
+		{nil, 5},             // Sin argumentos: estado del último comando
+		{[]string{"3"}, 3},   // Estado explícito
+		{[]string{"257"}, 1}, // Módulo 256
+		{[]string{"abc"}, 2}, // Argumento no numérico
+	}
+
+	for _, c := range casos {
+		var salida *SalidaShell
+		if err := ejecutarExit(sh, c.args, es); !errors.As(err, &salida) {
+			t.Errorf("exit %v: se esperaba *SalidaShell, obtenido: %v", c.args, err)
+		} else if salida.Estado != c.esperado {
+			t.Errorf("exit %v: estado esperado %d, obtenido %d", c.args, c.esperado, salida.Estado)
+		}
+	}
+
+	// Con más de un argumento la shell no debe terminar
+	var salida *SalidaShell
+	if err := ejecutarExit(sh, []string{"1", "2"}, es); err == nil || errors.As(err, &salida) {
+		t.Errorf("exit 1 2: se esperaba un error que no termine la shell, obtenido: %v", err)
+	}
+}
+
+// TestEjecutarTrap prueba que las trampas de señales se ejecuten desde el
+// bucle principal al atender las señales pendientes, y que las trampas ERR
+// y EXIT se disparen en el momento adecuado.
+func TestEjecutarTrap(t *testing.T) {
+	dir := t.TempDir()
+	marcaSenal := filepath.Join(dir, "senal")
+	marcaError := filepath.Join(dir, "error")
+	sh := NuevaShell()
+	var es EntradaSalida
+
+	// PASO 1: Definir las trampas (el comando no lleva comillas porque se
+	// reconstruye a partir de los argumentos anteriores a las señales)
+	if err := ejecutarTrap(sh, []string{"touch", marcaSenal, "TERM"}, es); err != nil {
+		t.Fatalf("trap TERM: error inesperado: %v", err)
+	}
+	defer ejecutarTrap(sh, []string{"-", "TERM"}, es)
+	if err := ejecutarTrap(sh, []string{"'touch", marcaError + "'", "ERR"}, es); err != nil {
+		t.Fatalf("trap ERR: error inesperado: %v", err)
+	}
+	if sh.trampas["ERR"] != "touch "+marcaError {
+		t.Errorf("Comando de la trampa ERR: %q", sh.trampas["ERR"])
+	}
+
+	// PASO 2: La señal queda encolada y el manejador corre al atenderla
+	proceso, _ := os.FindProcess(os.Getpid())
+	if err := proceso.Signal(syscall.SIGTERM); err != nil {
+		t.Skipf("No se pudo enviar la señal: %v", err)
+	}
+	select {
+	case s := <-sh.senales:
+		if err := sh.atenderSenal(s.(syscall.Signal)); err != nil {
+			t.Fatalf("atenderSenal: error inesperado: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("La señal no llegó al canal de la shell")
+	}
+	if _, err := os.Stat(marcaSenal); err != nil {
+		t.Errorf("La trampa TERM no se ejecutó: %v", err)
+	}
+
+	// PASO 3: La trampa ERR se ejecuta tras un comando fallido y conserva $?
+	sh.EjecutarLinea("false")
+	if _, err := os.Stat(marcaError); err != nil {
+		t.Errorf("La trampa ERR no se ejecutó: %v", err)
+	}
+	if sh.ultimoEstado != 1 {
+		t.Errorf("$? esperado tras false: 1, obtenido: %d", sh.ultimoEstado)
+	}
+
+	// PASO 4: Un exit dentro de la trampa EXIT cambia el estado de salida
+	ejecutarTrap(sh, []string{"'exit", "9'", "EXIT"}, es)
+	if estado := sh.ejecutarTrampaSalida(0); estado != 9 {
+		t.Errorf("Estado tras la trampa EXIT: esperado 9, obtenido %d", estado)
+	}
+
+	// PASO 5: Las señales que no pueden atraparse producen error y la
+	// trampa no queda definida
+	for _, senal := range []string{"KILL", "SIGSTOP"} {
+		if err := ejecutarTrap(sh, []string{"echo", senal}, es); err == nil {
+			t.Errorf("Se esperaba un error al atrapar %s", senal)
+		}
+	}
+	var listado strings.Builder
+	sh.mostrarTrampas([]string{"KILL", "STOP"}, &listado)
+	if listado.Len() != 0 {
+		t.Errorf("trap -p muestra trampas rechazadas: %q", listado.String())
+	}
+}
+
+// TestTrampasPorShell prueba que ignorar o restaurar una señal con trap solo
+// afecte a la shell que lo hace, y no al programa que la integra ni a otras
+// shells del mismo proceso.
+func TestTrampasPorShell(t *testing.T) {
+	usr1, ok := tablaSenales["USR1"]
+	if !ok {
+		t.Skip("El sistema no tiene SIGUSR1")
+	}
+
+	// El programa que integra las shells atiende SIGUSR1 por su cuenta
+	programa := make(chan os.Signal, 1)
+	signal.Notify(programa, usr1)
+	defer signal.Stop(programa)
+
+	primera, segunda := NuevaShell(), NuevaShell()
+	defer primera.Finalizar(0)
+	defer segunda.Finalizar(0)
+	primera.Run(context.Background(), "trap true USR1")
+	segunda.Run(context.Background(), "trap '' USR1")
+
+	// recibida envía SIGUSR1 al proceso e indica si llegó a cada destino
+	proceso, _ := os.FindProcess(os.Getpid())
+	recibida := func(canales ...chan os.Signal) []bool {
+		if err := proceso.Signal(usr1); err != nil {
+			t.Skipf("No se pudo enviar la señal: %v", err)
+		}
+		llegadas := make([]bool, len(canales))
+		for i, c := range canales {
+			select {
+			case <-c:
+				llegadas[i] = true
+			case <-time.After(500 * time.Millisecond):
+			}
+		}
+		return llegadas
+	}
+
+	// PASO 1: La señal llega al programa y a las dos shells, también a la que
+	// la ignora (que la descarta al atenderla)
+	if llegadas := recibida(programa, primera.senales, segunda.senales); !slices.Equal(llegadas, []bool{true, true, true}) {
+		t.Errorf("Con las dos trampas: %v", llegadas)
+	}
+	if err := segunda.atenderSenalesPendientes(); err != nil {
+		t.Errorf("Señal ignorada: %v", err)
+	}
+
+	// PASO 2: Restaurar la señal en una shell no afecta a la otra
+	primera.Run(context.Background(), "trap - USR1")
+	if llegadas := recibida(programa, primera.senales, segunda.senales); !slices.Equal(llegadas, []bool{true, false, true}) {
+		t.Errorf("Tras trap - USR1 en la primera: %v", llegadas)
+	}
+
+	// PASO 3: Sin trampas en ninguna shell, el programa sigue recibiéndola
+	segunda.Run(context.Background(), "trap - USR1")
+	if llegadas := recibida(programa, segunda.senales); !slices.Equal(llegadas, []bool{true, false}) {
+		t.Errorf("Sin trampas: %v", llegadas)
+	}
+}
+
+// TestAnalizarTuberia prueba el análisis de tuberías y redirecciones,
+// incluidos los errores de sintaxis.
+func TestAnalizarTuberia(t *testing.T) {
+	tub, err := AnalizarTuberia("sort < datos.txt | uniq -c >salida.txt 2>&1 &")
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	// VERIFICACIÓN 1: Dos comandos en segundo plano
+	if len(tub.Comandos) != 2 || !tub.SegundoPlano {
+		t.Fatalf("Se esperaban 2 comandos en segundo plano, obtenido: %+v", tub)
+	}
+
+	// VERIFICACIÓN 2: Nombre, argumentos y redirecciones de cada comando
+	sort, uniq := tub.Comandos[0], tub.Comandos[1]
+	if sort.Nombre != "sort" || len(sort.Args) != 0 {
+		t.Errorf("Primer comando inesperado: %+v", sort)
+	}
+	if len(sort.Redirecciones) != 1 || sort.Redirecciones[0] != (Redireccion{0, "<", "datos.txt"}) {
+		t.Errorf("Redirecciones de sort inesperadas: %+v", sort.Redirecciones)
+	}
+	if uniq.Nombre != "uniq" || !equal(uniq.Args, []string{"-c"}) {
+		t.Errorf("Segundo comando inesperado: %+v", uniq)
+	}
+	esperadas := []Redireccion{{1, ">", "salida.txt"}, {2, ">&", "1"}}
+	if len(uniq.Redirecciones) != 2 || uniq.Redirecciones[0] != esperadas[0] || uniq.Redirecciones[1] != esperadas[1] {
+		t.Errorf("Redirecciones de uniq esperadas: %+v, obtenidas: %+v", esperadas, uniq.Redirecciones)
+	}
+
+	// VERIFICACIÓN 3: Errores de sintaxis
+	for _, linea := range []string{"| ls", "ls |", "ls | | wc", "echo >", "&"} {
+		if _, err := AnalizarTuberia(linea); !errors.Is(err, errSintaxis) {
+			t.Errorf("%q: se esperaba un error de sintaxis, obtenido: %v", linea, err)
+		}
+	}
+}
+
+// TestOpcionesShell prueba la expansión de variables con nounset y el efecto
+// de las opciones pipefail, noclobber, errexit, xtrace y noexec al ejecutar
+// líneas completas.
+func TestOpcionesShell(t *testing.T) {
+	t.Setenv("GOSHELL_PRUEBA", "valor")
+	sh := NuevaShell()
+	var es EntradaSalida
+
+	// PASO 1: Expansión de variables
+	if p, _ := sh.expandirPalabra("a-$GOSHELL_PRUEBA-${GOSHELL_PRUEBA}b-$", false); p != "a-valor-valorb-$" {
+		t.Errorf("Expansión inesperada: %q", p)
+	}
+	if _, err := sh.expandirPalabra("$GOSHELL_NO_DEFINIDA", true); err == nil {
+		t.Error("Con nounset se esperaba un error para una variable sin definir")
+	}
+
+	// PASO 2: pipefail cambia el estado de una tubería
+	sh.EjecutarLinea("false | true")
+	if sh.ultimoEstado != 0 {
+		t.Errorf("Sin pipefail: estado esperado 0, obtenido %d", sh.ultimoEstado)
+	}
+	ejecutarSet(sh, []string{"-o", "pipefail"}, es)
+	sh.EjecutarLinea("false | true")
+	if sh.ultimoEstado != 1 {
+		t.Errorf("Con pipefail: estado esperado 1, obtenido %d", sh.ultimoEstado)
+	}
+
+	// PASO 3: noclobber impide sobrescribir con ">" pero no con ">|"
+	archivo := filepath.Join(t.TempDir(), "salida.txt")
+	ejecutarSet(sh, []string{"-C"}, es)
+	sh.EjecutarLinea("echo uno > " + archivo)
+	if err := sh.EjecutarLinea("echo dos > " + archivo); err == nil {
+		t.Error("Con noclobber se esperaba un error al sobrescribir")
+	}
+	sh.EjecutarLinea("echo tres >| " + archivo)
+	if contenido, _ := os.ReadFile(archivo); string(contenido) != "tres\n" {
+		t.Errorf("Contenido esperado %q, obtenido %q", "tres\n", contenido)
+	}
+
+	// PASO 4: errexit convierte un fallo en la salida de la shell
+	ejecutarSet(sh, []string{"-e"}, es)
+	var salida *SalidaShell
+	if err := sh.EjecutarLinea("false"); !errors.As(err, &salida) || salida.Estado != 1 {
+		t.Errorf("Con errexit se esperaba *SalidaShell con estado 1, obtenido: %v", err)
+	}
+
+	// PASO 5: xtrace muestra en stderr cada comando ya expandido, precedido
+	// por $PS4 ("+ " si no está definida)
+	var salidaRun, errores strings.Builder
+	sh = NuevaShell()
+	sh.Stdout = &salidaRun
+	sh.Stderr = &errores
+	sh.Env = []string{"PATH=" + os.Getenv("PATH"), "DIR=/tmp"}
+	sh.Run(context.Background(), "set -x\necho a $DIR\nset +x\necho b")
+	if errores.String() != "+ echo a /tmp\n+ set +x\n" || salidaRun.String() != "a /tmp\nb\n" {
+		t.Errorf("xtrace: stderr %q, stdout %q", errores.String(), salidaRun.String())
+	}
+	errores.Reset()
+	sh.Env = append(sh.Env, "PS4=>> ")
+	sh.Run(context.Background(), "set -x\necho a $DIR | cat")
+	if errores.String() != ">> echo a /tmp\n>> cat\n" {
+		t.Errorf("xtrace con PS4: stderr %q", errores.String())
+	}
+
+	// PASO 6: noexec no ejecuta nada en una shell no interactiva (tampoco un
+	// set +n posterior), pero se ignora en una interactiva
+	salidaRun.Reset()
+	sh = NuevaShell()
+	sh.Stdout = &salidaRun
+	if estado, _ := sh.Run(context.Background(), "set -n\necho no\nset +n\necho tampoco"); estado != 0 || salidaRun.Len() != 0 {
+		t.Errorf("noexec: estado %d, salida %q", estado, salidaRun.String())
+	}
+	sh = NuevaShell()
+	sh.Stdout = &salidaRun
+	sh.interactiva = true
+	sh.Run(context.Background(), "set -n\necho si")
+	if salidaRun.String() != "si\n" {
+		t.Errorf("noexec interactiva: salida %q", salidaRun.String())
+	}
+}
+
+// TestRegistroBuiltins prueba que el registro de comandos internos guíe la
+// ejecución, help y type, y que los comandos internos funcionen dentro de
+// tuberías y con redirecciones igual que los externos.
+func TestRegistroBuiltins(t *testing.T) {
+	sh := NuevaShell()
+	dir := t.TempDir()
+
+	// PASO 1: Registrar un comando interno propio y ejecutarlo por nombre
+	var recibidos []string
+	sh.builtins.Registrar(NuevoComandoInterno("saludar", "saludar [nombre]", "Saluda.",
+		func(sh *Shell, args []string, es EntradaSalida) error {
+			recibidos = args
+			_, err := io.WriteString(es.Salida, "hola "+strings.Join(args, " ")+"\n")
+			return err
+		}))
+	if err := sh.EjecutarComando("saludar", []string{"mundo"}, false); err != nil || !equal(recibidos, []string{"mundo"}) {
+		t.Errorf("saludar: error %v, argumentos %v", err, recibidos)
+	}
+
+	// PASO 2: El completado y help usan el registro
+	if nombres := sh.builtins.Completar("s"); !equal(nombres, []string{"saludar", "set", "shopt"}) {
+		t.Errorf("Completar(\"s\"): %v", nombres)
+	}
+
+	// PASO 3: Un comando interno con redirección escribe en el archivo
+	ayuda := filepath.Join(dir, "ayuda.txt")
+	if err := sh.EjecutarLinea("help sal > " + ayuda); err != nil {
+		t.Fatalf("help con redirección: error inesperado: %v", err)
+	}
+	if contenido, _ := os.ReadFile(ayuda); !strings.HasPrefix(string(contenido), "saludar: saludar [nombre]") {
+		t.Errorf("Ayuda inesperada: %q", contenido)
+	}
+
+	// PASO 4: Comandos internos y externos conectados por tuberías
+	salida := filepath.Join(dir, "tuberia.txt")
+	if err := sh.EjecutarLinea("type cd saludar | grep interno | wc -l > " + salida); err != nil {
+		t.Fatalf("Tubería con type: error inesperado: %v", err)
+	}
+	if contenido, _ := os.ReadFile(salida); strings.TrimSpace(string(contenido)) != "2" {
+		t.Errorf("Se esperaban 2 líneas de type, obtenido: %q", contenido)
+	}
+
+	// PASO 5: Los errores de un comando interno respetan 2> y fijan $?
+	errores := filepath.Join(dir, "errores.txt")
+	sh.EjecutarLinea("type no-existe-goshell 2> " + errores)
+	if contenido, _ := os.ReadFile(errores); !strings.Contains(string(contenido), "no-existe-goshell: no encontrado") {
+		t.Errorf("Mensaje de error inesperado: %q", contenido)
+	}
+	if sh.ultimoEstado != 1 {
+		t.Errorf("$? esperado tras type fallido: 1, obtenido %d", sh.ultimoEstado)
+	}
+
+	// PASO 6: exit dentro de una tubería no termina la shell
+	if err := sh.EjecutarLinea("exit 3 | true"); err != nil {
+		t.Errorf("exit en una tubería: error inesperado: %v", err)
+	}
+}
+
+// TestSubshellTuberia prueba que los comandos internos de una tubería corran
+// en una subshell: ven el estado de la shell pero no lo modifican.
+func TestSubshellTuberia(t *testing.T) {
+	dir := t.TempDir()
+	var salida strings.Builder
+	sh := NuevaShell()
+	sh.Stdout = &salida
+	sh.Stderr = io.Discard
+	sh.Dir = dir
+	sh.Env = []string{"PATH=" + os.Getenv("PATH")}
+	ctx := context.Background()
+
+	// PASO 1: Cada línea cambia el estado solo dentro de la tubería
+	lineas := []string{
+		"cd /tmp | cat",
+		"set -e | true",
+		"set -o pipefail | cat",
+		"trap 'echo x' USR1 | true",
+		"pushd / | cat",
+		"history -c | cat",
+	}
+	sh.agregarHistorial("echo uno")
+	for _, linea := range lineas {
+		if estado, err := sh.Run(ctx, linea); estado != 0 || err != nil {
+			t.Errorf("%q: estado %d, error %v", linea, estado, err)
+		}
+	}
+	if sh.Dir != dir || sh.opciones[optErrexit].Load() || sh.opciones[optPipefail].Load() {
+		t.Errorf("La shell cambió: Dir %q, errexit %v, pipefail %v", sh.Dir, sh.opciones[optErrexit].Load(), sh.opciones[optPipefail].Load())
+	}
+	if len(sh.trampas) != 0 || len(sh.pila) != 0 || sh.historial.Cantidad() != 1 {
+		t.Errorf("La shell cambió: trampas %v, pila %v, historial %d", sh.trampas, sh.pila, sh.historial.Cantidad())
+	}
+
+	// PASO 2: La subshell parte del estado de la shell
+	salida.Reset()
+	if sh.Run(ctx, "pwd | cat"); salida.String() != dir+"\n" {
+		t.Errorf("pwd | cat: esperado %q, obtenido %q", dir+"\n", salida.String())
+	}
+
+	// PASO 3: Sin tubería, los comandos internos cambian la shell
+	sh.Run(ctx, "cd /tmp")
+	if sh.Dir != "/tmp" {
+		t.Errorf("cd /tmp: Dir %q", sh.Dir)
+	}
+}
+
+// TestRunIntegrado prueba la shell como biblioteca: flujos, entorno y
+// directorio propios, estados de salida de Run y RunFile y cancelación.
+func TestRunIntegrado(t *testing.T) {
+	dir := t.TempDir()
+	var salida, errores strings.Builder
+	sh := NuevaShell()
+	sh.Stdout = &salida
+	sh.Stderr = &errores
+	sh.Env = []string{"PATH=" + os.Getenv("PATH"), "GOSHELL_SALUDO=mundo"}
+	sh.Dir = dir
+	ctx := context.Background()
+
+	// PASO 1: La expansión y los procesos usan el entorno de la shell
+	estado, err := sh.Run(ctx, "echo hola $GOSHELL_SALUDO | tr a-z A-Z\nenv | grep GOSHELL_\npwd")
+	if err != nil || estado != 0 {
+		t.Fatalf("Run: estado %d, error %v (stderr %q)", estado, err, errores.String())
+	}
+	lineas := strings.Split(strings.TrimSpace(salida.String()), "\n")
+	if len(lineas) != 3 || lineas[0] != "HOLA MUNDO" || lineas[1] != "GOSHELL_SALUDO=mundo" {
+		t.Fatalf("Salida inesperada: %q", salida.String())
+	}
+
+	// PASO 2: Los procesos externos se ejecutan en Dir
+	dirEval, _ := filepath.EvalSymlinks(dir)
+	if pwdEval, _ := filepath.EvalSymlinks(lineas[2]); pwdEval != dirEval {
+		t.Errorf("Directorio esperado: %q, obtenido: %q", dirEval, pwdEval)
+	}
+
+	// PASO 3: El estado es el del último comando; exit detiene el resto
+	if estado, _ := sh.Run(ctx, "true\nfalse"); estado != 1 {
+		t.Errorf("Estado esperado tras false: 1, obtenido %d", estado)
+	}
+	salida.Reset()
+	if estado, _ := sh.Run(ctx, "exit 7\necho no"); estado != 7 || salida.Len() != 0 {
+		t.Errorf("exit 7: estado %d, salida %q", estado, salida.String())
+	}
+
+	// PASO 4: Sin el programa en el PATH de la shell el estado es 127
+	sh.Env = []string{"PATH=" + dir}
+	if estado, _ := sh.Run(ctx, "ls"); estado != 127 {
+		t.Errorf("Estado esperado para un programa fuera del PATH: 127, obtenido %d", estado)
+	}
+	sh.Env = []string{"PATH=" + os.Getenv("PATH")}
+
+	// PASO 5: RunFile ignora los comentarios y ejecuta la trampa RETURN
+	script := filepath.Join(dir, "script.sh")
+	marca := filepath.Join(dir, "retorno")
+	os.WriteFile(script, []byte("#!/usr/bin/env goshell\n# comentario\ntrap 'touch "+marca+"' RETURN\nfalse\n"), 0o644)
+	if estado, err := sh.RunFile(ctx, script); err != nil || estado != 1 {
+		t.Errorf("RunFile: estado %d, error %v (stderr %q)", estado, err, errores.String())
+	}
+	if _, err := os.Stat(marca); err != nil {
+		t.Errorf("La trampa RETURN no se ejecutó: %v", err)
+	}
+
+	// PASO 6: Con el contexto cancelado no se ejecuta nada
+	cancelado, cancelar := context.WithCancel(ctx)
+	cancelar()
+	if _, err := sh.Run(cancelado, "echo no"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Se esperaba context.Canceled, obtenido: %v", err)
+	}
+}
+
+// TestDirectorioPorShell prueba que varias shells del mismo proceso tengan
+// cada una su directorio de trabajo, usado por los procesos externos, las
+// redirecciones y las rutas relativas de cd.
+func TestDirectorioPorShell(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	var shells [2]*Shell
+	var salidas [2]strings.Builder
+	for i := range shells {
+		dir := t.TempDir()
+		os.Mkdir(filepath.Join(dir, "sub"), 0o755)
+		shells[i] = NuevaShell()
+		shells[i].Dir = dir
+		shells[i].Stdout = &salidas[i]
+		shells[i].Stderr = io.Discard
+	}
+
+	for i, sh := range shells {
+		linea := "cd sub\necho " + strconv.Itoa(i) + " > marca.txt\npwd"
+		if estado, err := sh.Run(ctx, linea); estado != 0 || err != nil {
+			t.Fatalf("Shell %d: estado %d, error %v", i, estado, err)
+		}
+	}
+
+	for i, sh := range shells {
+		if filepath.Base(sh.Dir) != "sub" {
+			t.Errorf("Shell %d: directorio inesperado %q", i, sh.Dir)
+		}
+		contenido, err := os.ReadFile(filepath.Join(sh.Dir, "marca.txt"))
+		if err != nil || string(contenido) != strconv.Itoa(i)+"\n" {
+			t.Errorf("Shell %d: marca.txt = %q, %v", i, contenido, err)
+		}
+		dirEval, _ := filepath.EvalSymlinks(sh.Dir)
+		pwdEval, _ := filepath.EvalSymlinks(strings.TrimSpace(salidas[i].String()))
+		if pwdEval != dirEval {
+			t.Errorf("Shell %d: pwd esperado %q, obtenido %q", i, dirEval, pwdEval)
+		}
+	}
+}
+
+// TestCancelacion prueba que cancelar el contexto de Run detenga los procesos
+// externos (con SIGKILL si ignoran SIGTERM) y el comando interno timeout.
+func TestCancelacion(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skipf("No se encontró 'sleep': %v", err)
+	}
+	sh := NuevaShell()
+	sh.Stdin = strings.NewReader("")
+	sh.Stderr = io.Discard
+	sh.EsperaCancelacion = 100 * time.Millisecond
+
+	// Un script que ignora SIGTERM (también su hijo, que lo hereda) solo
+	// termina con el SIGKILL enviado a su grupo pasada la espera
+	script := filepath.Join(t.TempDir(), "ignora.sh")
+	os.WriteFile(script, []byte("#!/bin/sh\ntrap '' TERM\nsleep 10\n"), 0o755)
+
+	ctx, cancelar := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancelar()
+	inicio := time.Now()
+	estado, err := sh.Run(ctx, script)
+	if !errors.Is(err, context.DeadlineExceeded) || estado != 128+int(syscall.SIGKILL) {
+		t.Errorf("Run cancelado: estado %d, error %v", estado, err)
+	}
+	if duracion := time.Since(inicio); duracion > 5*time.Second {
+		t.Errorf("La cancelación tardó %v", duracion)
+	}
+
+	casos := []struct {
+		linea    string
+		esperado int
+	}{
+		{"timeout 0.1 sleep 10", 124},
+		{"timeout 5s true", 0},
+		{"timeout 0 false", 1},
+		{"timeout 1 no-existe-este-comando", 127},
+		{"timeout -1 true", 1},
+	}
+	for _, c := range casos {
+		if estado, err := sh.Run(context.Background(), c.linea); estado != c.esperado || err != nil {
+			t.Errorf("%q: estado esperado %d, obtenido %d (%v)", c.linea, c.esperado, estado, err)
+		}
+	}
+
+	// Al retirar el proceso con Wait se detiene el SIGKILL pendiente
+	sh.EsperaCancelacion = time.Hour
+	ctx, cancelar = context.WithCancel(context.Background())
+	proceso, _, err := sh.iniciarProceso(Comando{Nombre: "sleep", Args: []string{"10"}}, EntradaSalida{Contexto: ctx}, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancelar()
+	proceso.Wait()
+	if proceso.detencion == nil || proceso.detencion.Stop() {
+		t.Error("El SIGKILL pendiente no se detuvo al retirar el proceso")
+	}
+
+	// Un proceso ya retirado, o un grupo que ya no tiene miembros, no recibe
+	// señales: su PID (o su PGID) podría pertenecer a otro proceso
+	for _, grupo := range []int{0, proceso.Process.Pid} {
+		if detencion, err := sh.cancelarProceso(proceso.Process, grupo); detencion != nil || !errors.Is(err, os.ErrProcessDone) {
+			t.Errorf("Proceso retirado (grupo %d): temporizador %v, error %v", grupo, detencion, err)
+		}
+	}
+
+	// Un nieto que ignora SIGTERM recibe el SIGKILL del grupo aunque el
+	// script que lo lanzó ya haya terminado y sido retirado
+	marca := filepath.Join(t.TempDir(), "marca")
+	nieto := filepath.Join(t.TempDir(), "nieto.sh")
+	os.WriteFile(nieto, []byte("#!/bin/sh\nsh -c 'trap \"\" TERM; sleep 1.5; echo SOBREVIVIO > "+marca+"' &\nsleep 30\n"), 0o755)
+	sh.EsperaCancelacion = 300 * time.Millisecond
+	ctx, cancelar = context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancelar()
+	sh.Run(ctx, nieto)
+	time.Sleep(2500 * time.Millisecond)
+	if contenido, err := os.ReadFile(marca); err == nil {
+		t.Errorf("El nieto que ignora SIGTERM sobrevivió a la cancelación: %q", contenido)
+	}
+}
+
+// TestComandoNoEncontrado prueba el mensaje y el estado de un comando que no
+// existe, las sugerencias por distancia de edición y command_not_found_handle.
+func TestComandoNoEncontrado(t *testing.T) {
+	// Un PATH con un único programa "git"
+	dirPath := t.TempDir()
+	os.WriteFile(filepath.Join(dirPath, "git"), []byte("#!/bin/sh\n"), 0o755)
+	os.WriteFile(filepath.Join(dirPath, "gitk"), []byte("no ejecutable"), 0o644)
+
+	sh := NuevaShell()
+	var errores strings.Builder
+	sh.Stderr = &errores
+	sh.Env = []string{"PATH=" + dirPath}
+	sh.Dir = t.TempDir()
+
+	estado, _ := sh.Run(context.Background(), "gti status")
+	esperado := "goshell: gti: command not found\n¿Quisiste decir alguno de estos comandos?\n    git\n"
+	if estado != 127 || errores.String() != esperado {
+		t.Errorf("gti: estado %d, mensaje %q", estado, errores.String())
+	}
+
+	// Sin nombres parecidos no hay sugerencias; el mensaje respeta 2>
+	errores.Reset()
+	estado, _ = sh.Run(context.Background(), "xyzzy 2> errores.txt")
+	contenido, _ := os.ReadFile(filepath.Join(sh.Dir, "errores.txt"))
+	if estado != 127 || errores.Len() != 0 || string(contenido) != "goshell: xyzzy: command not found\n" {
+		t.Errorf("xyzzy: estado %d, stderr %q, archivo %q", estado, errores.String(), contenido)
+	}
+
+	// Los comandos internos también se sugieren
+	if s := sh.sugerencias("tarp"); !equal(s, []string{"trap"}) {
+		t.Errorf("Sugerencias para tarp: %v", s)
+	}
+
+	// Un command_not_found_handle registrado reemplaza al mensaje
+	var recibidos []string
+	sh.Builtins().Registrar(NuevoComandoInterno(manejadorNoEncontrado, "", "",
+		func(sh *Shell, args []string, es EntradaSalida) error {
+			recibidos = args
+			return EstadoSalida(3)
+		}))
+	errores.Reset()
+	if estado, _ := sh.Run(context.Background(), "gti status"); estado != 3 || errores.Len() != 0 {
+		t.Errorf("Manejador: estado %d, stderr %q", estado, errores.String())
+	}
+	if !equal(recibidos, []string{"gti", "status"}) {
+		t.Errorf("Manejador: argumentos %v", recibidos)
+	}
+
+	casos := []struct {
+		a, b     string
+		esperado int
+	}{
+		{"gti", "git", 1},
+		{"git", "git", 0},
+		{"sl", "ls", 1},
+		{"grpe", "grep", 1},
+		{"pyhton3", "python3", 1},
+		{"", "ls", 2},
+		{"ñu", "nu", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, c := range casos {
+		if d := distanciaEdicion(c.a, c.b); d != c.esperado {
+			t.Errorf("distanciaEdicion(%q, %q) = %d, esperado %d", c.a, c.b, d, c.esperado)
+		}
+	}
+}
+
+// TestTablaHash prueba que la shell recuerde las rutas de los programas, que
+// las olvide al cambiar el PATH, el comando interno hash, type -a y el índice
+// de programas del PATH.
+func TestTablaHash(t *testing.T) {
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	for _, dir := range []string{dir1, dir2} {
+		os.WriteFile(filepath.Join(dir, "prog"), []byte("#!/bin/sh\n"), 0o755)
+	}
+
+	sh := NuevaShell()
+	var salida strings.Builder
+	sh.Stdout = &salida
+	sh.Stderr = io.Discard
+	sh.Env = []string{"PATH=" + dir1 + string(filepath.ListSeparator) + dir2}
+	ctx := context.Background()
+
+	// PASO 1: Cada ejecución cuenta un uso de la ruta recordada
+	sh.Run(ctx, "prog\nprog\nhash")
+	esperado := "usos\tcomando\n   2\t" + filepath.Join(dir1, "prog") + "\n"
+	if salida.String() != esperado {
+		t.Errorf("hash: esperado %q, obtenido %q", esperado, salida.String())
+	}
+
+	// PASO 2: Una ruta recordada con -p tiene prioridad sobre el PATH
+	salida.Reset()
+	sh.Run(ctx, "hash -p "+filepath.Join(dir2, "prog")+" prog\ntype prog\ntype -a prog")
+	esperado = "prog está en la tabla hash (" + filepath.Join(dir2, "prog") + ")\n" +
+		"prog es " + filepath.Join(dir1, "prog") + "\n" +
+		"prog es " + filepath.Join(dir2, "prog") + "\n"
+	if salida.String() != esperado {
+		t.Errorf("type: esperado %q, obtenido %q", esperado, salida.String())
+	}
+
+	// PASO 3: Cambiar el PATH descarta la tabla
+	sh.Env = append(sh.Env, "PATH="+dir2)
+	if estado, _ := sh.Run(ctx, "hash -t prog"); estado != 1 {
+		t.Errorf("hash -t tras cambiar el PATH: estado %d", estado)
+	}
+
+	// PASO 4: El índice incluye los programas nuevos después de hash -r
+	if programas := sh.programasPath(); !equal(programas, []string{"prog"}) {
+		t.Errorf("Índice del PATH: %v", programas)
+	}
+	os.WriteFile(filepath.Join(dir2, "otro"), []byte("#!/bin/sh\n"), 0o755)
+	sh.Run(ctx, "hash -r")
+	if programas := sh.programasPath(); !equal(programas, []string{"otro", "prog"}) {
+		t.Errorf("Índice del PATH tras hash -r: %v", programas)
+	}
+}
+
+// TestResolucionComandos prueba type con sus opciones, command y builtin, que
+// siguen el mismo orden de resolución que EjecutarComando.
+func TestResolucionComandos(t *testing.T) {
+	dir := t.TempDir()
+	for _, nombre := range []string{"prog", "wait"} {
+		os.WriteFile(filepath.Join(dir, nombre), []byte("#!/bin/sh\necho programa\n"), 0o755)
+	}
+
+	sh := NuevaShell()
+	sh.Env = []string{"PATH=" + dir}
+	sh.Stderr = io.Discard
+	prog, wait := filepath.Join(dir, "prog"), filepath.Join(dir, "wait")
+
+	casos := []struct {
+		linea    string
+		salida   string
+		esperado int
+	}{
+		{"type -t wait prog", "builtin\nfile\n", 0},
+		{"type -at wait", "builtin\nfile\n", 0},
+		{"type -p wait prog", prog + "\n", 0},
+		{"type -a wait", "wait es un comando interno de la shell\nwait es " + wait + "\n", 0},
+		{"type -t no-existe", "", 1},
+		{"type -z prog", "", 1},
+		{"command -v wait prog no-existe", "wait\n" + prog + "\n", 1},
+		{"command -V prog", "prog está en la tabla hash (" + prog + ")\n", 0},
+		{"command prog", "programa\n", 0},
+		{"command no-existe", "", 127},
+		{"builtin type -t prog", "file\n", 0},
+		{"builtin prog", "", 1},
+	}
+	for _, c := range casos {
+		var salida strings.Builder
+		sh.Stdout = &salida
+		if estado, _ := sh.Run(context.Background(), c.linea); estado != c.esperado || salida.String() != c.salida {
+			t.Errorf("%q: esperado (%d, %q), obtenido (%d, %q)", c.linea, c.esperado, c.salida, estado, salida.String())
+		}
+	}
+}
+
+// TestCdVariablesYRutas prueba cd -, las variables PWD y OLDPWD, la búsqueda
+// en CDPATH, las rutas lógicas y físicas (-L y -P) y los errores de uso.
+func TestCdVariablesYRutas(t *testing.T) {
+	// PASO 1: Preparar base/real/sub, un enlace base/enlace -> base/real/sub
+	// y un directorio proyectos/app para CDPATH
+	base, _ := filepath.EvalSymlinks(t.TempDir())
+	os.MkdirAll(filepath.Join(base, "real", "sub"), 0o755)
+	os.MkdirAll(filepath.Join(base, "proyectos", "app"), 0o755)
+	enlace := filepath.Join(base, "enlace")
+	if err := os.Symlink(filepath.Join(base, "real", "sub"), enlace); err != nil {
+		t.Skipf("No se pudo crear el enlace simbólico: %v", err)
+	}
+
+	sh := NuevaShell()
+	var salida strings.Builder
+	sh.Stdout = &salida
+	sh.Stderr = io.Discard
+	sh.Env = []string{"HOME=" + base, "CDPATH=" + filepath.Join(base, "proyectos")}
+	sh.Dir = base
+	ctx := context.Background()
+
+	pwd := func() string {
+		v, _ := sh.variable("PWD")
+		return v
+	}
+
+	// PASO 2: Ruta lógica: ".." desde el enlace vuelve a base
+	sh.Run(ctx, "cd enlace\ncd ..")
+	if sh.Dir != base || pwd() != base {
+		t.Errorf("cd -L ..: Dir %q, PWD %q", sh.Dir, pwd())
+	}
+
+	// PASO 3: Ruta física: el enlace se resuelve y ".." lleva a real
+	sh.Run(ctx, "cd -P enlace\ncd ..")
+	if real := filepath.Join(base, "real"); sh.Dir != real {
+		t.Errorf("cd -P: Dir %q, esperado %q", sh.Dir, real)
+	}
+
+	// Con -P, ".." se aplica sobre el directorio real aunque el enlace esté
+	// en el destino o en el directorio actual
+	for _, linea := range []string{"cd " + base + "\ncd -P enlace/..", "cd " + base + "\ncd enlace\ncd -P .."} {
+		sh.Run(ctx, linea)
+		if real := filepath.Join(base, "real"); sh.Dir != real {
+			t.Errorf("%q: Dir %q, esperado %q", linea, sh.Dir, real)
+		}
+	}
+
+	// PASO 4: cd - vuelve al anterior y lo muestra; OLDPWD queda actualizado
+	sh.Run(ctx, "cd")
+	salida.Reset()
+	sh.Run(ctx, "cd -")
+	oldpwd, _ := sh.variable("OLDPWD")
+	if sh.Dir != filepath.Join(base, "real") || salida.String() != sh.Dir+"\n" || oldpwd != base {
+		t.Errorf("cd -: Dir %q, salida %q, OLDPWD %q", sh.Dir, salida.String(), oldpwd)
+	}
+
+	// PASO 5: CDPATH encuentra app desde cualquier directorio y muestra la ruta
+	salida.Reset()
+	sh.Run(ctx, "cd app")
+	if app := filepath.Join(base, "proyectos", "app"); sh.Dir != app || salida.String() != app+"\n" {
+		t.Errorf("cd con CDPATH: Dir %q, salida %q", sh.Dir, salida.String())
+	}
+
+	// PASO 6: Errores de uso; el directorio no cambia
+	antes := sh.Dir
+	for _, linea := range []string{"cd a b", "cd -x", "cd ./app"} {
+		if estado, _ := sh.Run(ctx, linea); estado != 1 || sh.Dir != antes {
+			t.Errorf("%q: estado %d, Dir %q", linea, estado, sh.Dir)
+		}
+	}
+}
+
+// TestPilaDirectorios prueba pushd, popd y dirs con rotaciones +N y -N, y que
+// la pila se guarde y se recupere entre sesiones.
+func TestPilaDirectorios(t *testing.T) {
+	base, _ := filepath.EvalSymlinks(t.TempDir())
+	for _, d := range []string{"a", "b", "c"} {
+		os.Mkdir(filepath.Join(base, d), 0o755)
+	}
+
+	sh := NuevaShell()
+	var salida strings.Builder
+	sh.Stdout = &salida
+	sh.Stderr = io.Discard
+	sh.Env = []string{"HOME=" + base, "XDG_STATE_HOME=" + filepath.Join(base, "estado")}
+	sh.Dir = base
+
+	casos := []struct {
+		linea    string
+		salida   string
+		esperado int
+	}{
+		{"popd", "", 1},
+		{"pushd", "", 1},
+		{"pushd a", "~/a ~\n", 0},
+		{"pushd ../b", "~/b ~/a ~\n", 0},
+		{"pushd " + filepath.Join(base, "c"), "~/c ~/b ~/a ~\n", 0},
+		{"dirs -v", " 0  ~/c\n 1  ~/b\n 2  ~/a\n 3  ~\n", 0},
+		{"pushd +2", "~/a ~ ~/c ~/b\n", 0},
+		{"pushd -0", "~/b ~/a ~ ~/c\n", 0},
+		{"pushd", "~/a ~/b ~ ~/c\n", 0},
+		{"popd +2", "~/a ~/b ~/c\n", 0},
+		{"popd -0", "~/a ~/b\n", 0},
+		{"dirs +1 -l", filepath.Join(base, "b") + "\n", 0},
+		{"pushd +5", "", 1},
+		{"popd", "~/b\n", 0},
+	}
+	for _, c := range casos {
+		salida.Reset()
+		if estado, _ := sh.Run(context.Background(), c.linea); estado != c.esperado || salida.String() != c.salida {
+			t.Errorf("%q: esperado (%d, %q), obtenido (%d, %q)", c.linea, c.esperado, c.salida, estado, salida.String())
+		}
+	}
+	if sh.Dir != filepath.Join(base, "b") {
+		t.Errorf("Directorio final: %q", sh.Dir)
+	}
+
+	// La pila se guarda al terminar y la siguiente sesión la recupera,
+	// descartando los directorios que ya no existen
+	sh.Run(context.Background(), "pushd ../a\npushd ../c")
+	if err := sh.guardarPila(); err != nil {
+		t.Fatalf("Error al guardar la pila: %v", err)
+	}
+	os.Remove(filepath.Join(base, "b"))
+	otra := NuevaShell()
+	otra.Env = sh.Env
+	otra.cargarPila()
+	if !equal(otra.pila, []string{filepath.Join(base, "a")}) {
+		t.Errorf("Pila recuperada: %v", otra.pila)
+	}
+}
+
+// TestFrecencia prueba que la shell interactiva registre los directorios que
+// visita y que z cambie al de mayor frecencia, los liste y los olvide.
+func TestFrecencia(t *testing.T) {
+	base, _ := filepath.EvalSymlinks(t.TempDir())
+	for _, d := range []string{"proyecto/src", "otro/src", "docs"} {
+		os.MkdirAll(filepath.Join(base, d), 0o755)
+	}
+
+	sh := NuevaShell()
+	var salida strings.Builder
+	sh.Stdout = &salida
+	sh.Stderr = io.Discard
+	sh.Env = []string{"HOME=" + base, "XDG_DATA_HOME=" + filepath.Join(base, "datos")}
+	sh.Dir = base
+
+	// Una shell no interactiva no modifica la base de datos
+	sh.Run(context.Background(), "cd docs\ncd ..")
+	if _, err := os.Stat(filepath.Join(base, "datos", "goshell", "z")); err == nil {
+		t.Fatal("Una shell no interactiva registró visitas")
+	}
+
+	sh.interactiva = true
+	sh.Run(context.Background(), "cd proyecto/src\ncd ../..\ncd proyecto/src\ncd ../..\ncd otro/src\ncd docs")
+
+	casos := []struct {
+		linea    string
+		dir      string
+		esperado int
+	}{
+		{"z src", filepath.Join(base, "proyecto", "src"), 0},
+		{"z OTRO src", filepath.Join(base, "otro", "src"), 0},
+		{"z proyecto", filepath.Join(base, "otro", "src"), 1}, // El último patrón va en el último componente
+		{"z inexistente", filepath.Join(base, "otro", "src"), 1},
+	}
+	for _, c := range casos {
+		if estado, _ := sh.Run(context.Background(), c.linea); estado != c.esperado || sh.Dir != c.dir {
+			t.Errorf("%q: esperado (%d, %q), obtenido (%d, %q)", c.linea, c.esperado, c.dir, estado, sh.Dir)
+		}
+	}
+
+	// z -l lista los candidatos con el mejor al final; z -x los olvida
+	salida.Reset()
+	sh.Run(context.Background(), "z -l src")
+	lineas := strings.Split(strings.TrimSpace(salida.String()), "\n")
+	if len(lineas) != 2 || !strings.HasSuffix(lineas[1], filepath.Join(base, "proyecto", "src")) {
+		t.Errorf("z -l src: %q", salida.String())
+	}
+	if estado, _ := sh.Run(context.Background(), "z -x "+filepath.Join(base, "proyecto", "src")); estado != 0 {
+		t.Errorf("z -x: estado %d", estado)
+	}
+	if estado, _ := sh.Run(context.Background(), "z src"); estado != 0 || sh.Dir != filepath.Join(base, "otro", "src") {
+		t.Errorf("z src tras z -x: (%d, %q)", estado, sh.Dir)
+	}
+}
+
+// TestShoptAutocdCdspell prueba shopt y las opciones autocd y cdspell, que
+// solo actúan en la shell interactiva.
+func TestShoptAutocdCdspell(t *testing.T) {
+	base, _ := filepath.EvalSymlinks(t.TempDir())
+	for _, d := range []string{"proyectos/goshell", "documentos", "docs"} {
+		os.MkdirAll(filepath.Join(base, d), 0o755)
+	}
+
+	sh := NuevaShell()
+	var salida, errores strings.Builder
+	sh.Stdout = &salida
+	sh.Stderr = &errores
+	sh.Env = []string{"HOME=" + base, "PATH="}
+	sh.Dir = base
+
+	// PASO 1: shopt activa, consulta y lista las opciones
+	casos := []struct {
+		linea    string
+		salida   string
+		esperado int
+	}{
+		{"shopt -q autocd", "", 1},
+		{"shopt -s autocd cdspell", "", 0},
+		{"shopt -q autocd cdspell", "", 0},
+		{"shopt autocd", "autocd         \ton\n", 0},
+		{"shopt -u cdspell", "", 0},
+		{"shopt -p cdspell", "shopt -u cdspell\n", 1},
+		{"shopt -s errexit", "", 1},
+		{"shopt -po pipefail", "shopt -u -o pipefail\n", 1},
+	}
+	for _, c := range casos {
+		salida.Reset()
+		if estado, _ := sh.Run(context.Background(), c.linea); estado != c.esperado || salida.String() != c.salida {
+			t.Errorf("%q: esperado (%d, %q), obtenido (%d, %q)", c.linea, c.esperado, c.salida, estado, salida.String())
+		}
+	}
+	salida.Reset()
+	sh.Run(context.Background(), "set -o")
+	if strings.Contains(salida.String(), "autocd") {
+		t.Errorf("set -o muestra las opciones de shopt: %q", salida.String())
+	}
+
+	// PASO 2: Sin una shell interactiva, autocd no actúa
+	if estado, _ := sh.Run(context.Background(), "documentos"); estado != 127 || sh.Dir != base {
+		t.Errorf("autocd no interactivo: (%d, %q)", estado, sh.Dir)
+	}
+
+	// PASO 3: autocd cambia al directorio y muestra el cd equivalente
+	sh.interactiva = true
+	errores.Reset()
+	if estado, _ := sh.Run(context.Background(), "proyectos/goshell"); estado != 0 || sh.Dir != filepath.Join(base, "proyectos", "goshell") {
+		t.Errorf("autocd: (%d, %q)", estado, sh.Dir)
+	}
+	if errores.String() != "cd -- proyectos/goshell\n" {
+		t.Errorf("autocd: mensaje %q", errores.String())
+	}
+
+	// PASO 4: cdspell corrige un carácter por componente y muestra el
+	// directorio elegido; sin la opción o con un empate, cd falla
+	sh.Dir = base
+	if estado, _ := sh.Run(context.Background(), "cd proyetcos/gohsell"); estado != 1 {
+		t.Errorf("cd sin cdspell: estado %d", estado)
+	}
+	sh.Run(context.Background(), "shopt -s cdspell")
+	salida.Reset()
+	if estado, _ := sh.Run(context.Background(), "cd proyetcos/gohsell"); estado != 0 || sh.Dir != filepath.Join(base, "proyectos", "goshell") {
+		t.Errorf("cdspell: (%d, %q)", estado, sh.Dir)
+	}
+	if salida.String() != filepath.Join("proyectos", "goshell")+"\n" {
+		t.Errorf("cdspell: salida %q", salida.String())
+	}
+	if estado, _ := sh.Run(context.Background(), "cd "+filepath.Join(base, "documentso")); estado != 0 || sh.Dir != filepath.Join(base, "documentos") {
+		t.Errorf("cdspell absoluto: (%d, %q)", estado, sh.Dir)
+	}
+	sh.Dir = base
+	os.Mkdir(filepath.Join(base, "dics"), 0o755)
+	if estado, _ := sh.Run(context.Background(), "cd dacs"); estado != 1 || sh.Dir != base {
+		t.Errorf("cdspell con empate: (%d, %q)", estado, sh.Dir)
+	}
+}
+
+// TestPwd prueba que pwd muestre la ruta lógica que siguió cd y que pwd -P
+// resuelva los enlaces simbólicos.
+func TestPwd(t *testing.T) {
+	base, _ := filepath.EvalSymlinks(t.TempDir())
+	real := filepath.Join(base, "real")
+	enlace := filepath.Join(base, "enlace")
+	os.Mkdir(real, 0o755)
+	if err := os.Symlink(real, enlace); err != nil {
+		t.Skipf("No se pueden crear enlaces simbólicos: %v", err)
+	}
+
+	sh := NuevaShell()
+	var salida strings.Builder
+	sh.Stdout = &salida
+	sh.Stderr = io.Discard
+	sh.Dir = base
+	sh.Run(context.Background(), "cd enlace")
+
+	casos := []struct {
+		linea    string
+		salida   string
+		esperado int
+	}{
+		{"pwd", enlace + "\n", 0},
+		{"pwd -L", enlace + "\n", 0},
+		{"pwd -P", real + "\n", 0},
+		{"pwd -P -L", enlace + "\n", 0},
+		{"pwd -X", "", 1},
+		{"cd -P . > /dev/null\npwd", real + "\n", 0},
+	}
+	for _, c := range casos {
+		salida.Reset()
+		if estado, _ := sh.Run(context.Background(), c.linea); estado != c.esperado || salida.String() != c.salida {
+			t.Errorf("%q: esperado (%d, %q), obtenido (%d, %q)", c.linea, c.esperado, c.salida, estado, salida.String())
+		}
+	}
+
+	// Si el directorio desaparece, pwd -P informa el error
+	sh.Dir = filepath.Join(base, "borrado")
+	if estado, _ := sh.Run(context.Background(), "pwd -P"); estado != 1 {
+		t.Errorf("pwd -P en un directorio inexistente: estado %d", estado)
+	}
+}
+
+// editarTeclas ejecuta el editor de línea con las teclas dadas, sin terminal
+func editarTeclas(sh *Shell, teclas string) (string, error) {
+	sh.Stdout = io.Discard
+	ed := sh.nuevoEditor(bufio.NewReader(strings.NewReader(teclas)))
+	ed.ancho = func() int { return 80 }
+	return ed.editar()
+}
+
+// TestEditorLinea prueba las teclas del editor de línea: movimiento, cortes
+// con el anillo, pegado, deshacer y caracteres anchos o combinados.
+func TestEditorLinea(t *testing.T) {
+	casos := []struct {
+		nombre string
+		teclas string
+		linea  string
+	}{
+		{"Enter", "hola\r", "hola\n"},
+		{"Ctrl-A", "mundo\x01hola \r", "hola mundo\n"},
+		{"Flechas", "ac\x1b[Db\x1b[C\x1b[Cd\r", "abcd\n"},
+		{"Inicio y Suprimir", "ab\x1b[H\x1b[3~\r", "b\n"},
+		{"Ctrl-W seguidos se juntan", "uno dos tres\x17\x17\x19\r", "uno dos tres\n"},
+		{"Alt-B y Ctrl-K", "uno dos\x1bb\x0b\x01\x19 \r", "dos uno \n"},
+		{"Alt-F y Alt-D", "uno dos tres\x01\x1bf\x1bd\r", "uno tres\n"},
+		{"Ctrl-U y Alt-Y", "a\x15b\x15\x19\x1by\r", "a\n"},
+		{"Ctrl-T", "ab\x14\r", "ba\n"},
+		{"Deshacer borrado", "abc\x7f\x7f\x1f\r", "ab\n"},
+		{"Deshacer escritura", "abc\x18\x15\r", "\n"},
+		{"Caracteres anchos", "日本\x02\x02X\r", "X日本\n"},
+		{"Marcas combinantes", "e\u0301a\x02\x7f\r", "a\n"},
+		{"Caracteres de control", "a\x00\x1cb\r", "ab\n"},
+	}
+	for _, c := range casos {
+		linea, err := editarTeclas(NuevaShell(), c.teclas)
+		if err != nil || linea != c.linea {
+			t.Errorf("%s: esperado %q, obtenido (%q, %v)", c.nombre, c.linea, linea, err)
+		}
+	}
+
+	// Ctrl-D en una línea vacía termina la entrada; Ctrl-C descarta la línea
+	if _, err := editarTeclas(NuevaShell(), "\x04"); !errors.Is(err, io.EOF) {
+		t.Errorf("Ctrl-D: %v", err)
+	}
+	if _, err := editarTeclas(NuevaShell(), "abc\x03"); !errors.Is(err, errInterrupcion) {
+		t.Errorf("Ctrl-C: %v", err)
+	}
+
+	// Un carácter ancho que no cabe al final de una fila pasa a la siguiente
+	esperado := []punto{{0, 0}, {0, 1}, {1, 0}, {1, 2}, {2, 0}}
+	for i, p := range ubicar([]int{1, 1, 2, 1}, 3) {
+		if p != esperado[i] {
+			t.Errorf("ubicar: posición %d: esperado %v, obtenido %v", i, esperado[i], p)
+		}
+	}
+	if ancho := anchoTexto("añ日本\u0301😀"); ancho != 8 {
+		t.Errorf("anchoTexto: %d", ancho)
+	}
+}
+
+// TestEditorVi prueba el modo vi del editor de línea: movimientos,
+// operadores con cuentas, repetición con ".", el modo en el prompt y la
+// edición de la línea en un editor externo.
+func TestEditorVi(t *testing.T) {
+	// Un ESC seguido de otra tecla en el mismo búfer llega como Alt, que en
+	// modo inserción también pasa al modo normal
+	casos := []struct {
+		nombre string
+		teclas string
+		linea  string
+	}{
+		{"Inserción", "hola\x1b0iX\r", "Xhola\n"},
+		{"Inserción con atajos de emacs", "uno dos\x17tres\r", "uno tres\n"},
+		{"dw", "uno dos tres\x1b0dw\r", "dos tres\n"},
+		{"Cuenta", "uno dos tres\x1b02dw\r", "tres\n"},
+		{"Cuentas multiplicadas", "a b c d e\x1b02d2w\r", "e\n"},
+		{"d2e", "uno dos tres\x1b0d2e\r", " tres\n"},
+		{"cw", "uno dos tres\x1bbcwXX\x1b\r", "uno dos XX\n"},
+		{"f y ;", "a=b=c\x1b0f=;D\r", "a=b\n"},
+		{"dt", "abc\x1b0dtc\r", "c\n"},
+		{"dF", "abc\x1b$dFa\r", "c\n"},
+		{"yw y p", "uno \x1b0yw$p\r", "uno uno \n"},
+		{"~ y r", "abc\x1b03~0rx\r", "xBC\n"},
+		{"dd", "uno\x1bddinuevo\r", "nuevo\n"},
+		{"cc", "uno\x1bccdos\r", "dos\n"},
+		{"I y A", "  dos\x1bIuno\x1bAtres\r", "  unodostres\n"},
+		{"Deshacer", "uno dos\x1b0dwu\r", "uno dos\n"},
+		{"Deshacer un cambio completo", "uno\x1b0cwdos\x1bu\r", "uno\n"},
+		{"Repetir x", "a b c\x1b0x.\r", "b c\n"},
+		{"Repetir con otra cuenta", "a b c d\x1b0x3.\r", "c d\n"},
+		{"Repetir cw", "uno dos tres\x1b0cwUNO\x1bw.\r", "UNO UNO tres\n"},
+	}
+	for _, c := range casos {
+		sh := NuevaShell()
+		sh.opciones[optVi].Store(true)
+		linea, err := editarTeclas(sh, c.teclas)
+		if err != nil || linea != c.linea {
+			t.Errorf("%s: esperado %q, obtenido (%q, %v)", c.nombre, c.linea, linea, err)
+		}
+	}
+
+	// Ctrl-D en una línea vacía termina la entrada también en modo normal
+	sh := NuevaShell()
+	sh.opciones[optVi].Store(true)
+	if _, err := editarTeclas(sh, "\x1b\x04"); !errors.Is(err, io.EOF) {
+		t.Errorf("Ctrl-D: %v", err)
+	}
+
+	// El prompt muestra el modo activo
+	ed := sh.nuevoEditor(bufio.NewReader(strings.NewReader("")))
+	sh.promptActual = "$ "
+	if p := ed.textoPrompt(); p != "(ins) $ " {
+		t.Errorf("prompt en modo inserción: %q", p)
+	}
+	ed.viNormal = true
+	if p := ed.textoPrompt(); p != "(cmd) $ " {
+		t.Errorf("prompt en modo normal: %q", p)
+	}
+	sh.opciones[optVi].Store(false)
+	if p := ed.textoPrompt(); p != "$ " {
+		t.Errorf("prompt en modo emacs: %q", p)
+	}
+
+	// v abre la línea en $EDITOR y acepta lo que se guardó
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skipf("No se pudo ejecutar 'sh': %v", err)
+	}
+	editor := filepath.Join(t.TempDir(), "editor")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\necho \"$(cat \"$1\") uno\" > \"$1\"\necho 'echo dos' >> \"$1\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sh = NuevaShell()
+	sh.opciones[optVi].Store(true)
+	sh.Env = []string{"PATH=" + os.Getenv("PATH"), "EDITOR=" + editor}
+	linea, err := editarTeclas(sh, "echo\x1bv")
+	if err != nil || linea != "echo uno\necho dos\n" {
+		t.Errorf("v: obtenido (%q, %v)", linea, err)
+	}
+}
+
+// TestHistorial prueba el historial: HISTCONTROL, HISTSIZE, el comando
+// history con sus opciones, el archivo con horas y las flechas del editor.
+func TestHistorial(t *testing.T) {
+	archivo := filepath.Join(t.TempDir(), "historial")
+	sh := NuevaShell()
+	var salida strings.Builder
+	sh.Stdout = &salida
+	sh.Stderr = io.Discard
+	sh.Env = []string{"HISTFILE=" + archivo, "HISTCONTROL=ignoreboth:erasedups", "HISTSIZE=4"}
+
+	// PASO 1: HISTCONTROL descarta espacios y duplicados; HISTSIZE limita
+	// las entradas sin cambiar la numeración de las que quedan
+	for _, linea := range []string{"echo uno\n", "echo dos", "echo dos", " secreto", "echo uno", "ls", "pwd", "date"} {
+		sh.agregarHistorial(linea)
+	}
+	casos := []struct {
+		linea    string
+		salida   string
+		esperado int
+	}{
+		{"history", "    2  echo uno\n    3  ls\n    4  pwd\n    5  date\n", 0},
+		{"history 2", "    4  pwd\n    5  date\n", 0},
+		{"history -d 3", "", 0},
+		{"history -d -1", "", 0},
+		{"history", "    2  echo uno\n    3  pwd\n", 0},
+		{"history -d 9", "", 1},
+		{"history x", "", 1},
+		{"history -w", "", 0},
+		{"history -c", "", 0},
+		{"history", "", 0},
+		{"history -r", "", 0},
+		{"history", "    1  echo uno\n    2  pwd\n", 0},
+	}
+	for _, c := range casos {
+		salida.Reset()
+		if estado, _ := sh.Run(context.Background(), c.linea); estado != c.esperado || salida.String() != c.salida {
+			t.Errorf("%q: esperado (%d, %q), obtenido (%d, %q)", c.linea, c.esperado, c.salida, estado, salida.String())
+		}
+	}
+
+	// PASO 2: Con HISTTIMEFORMAT el archivo guarda la hora de cada entrada
+	sh.definirVariable("HISTTIMEFORMAT", "%Y-%m-%d %H:%M:%S %% ")
+	sh.historial.Vaciar()
+	hora := time.Date(2024, 3, 5, 14, 7, 9, 0, time.Local)
+	sh.historial.Agregar("make", hora, controlHistorial{}, -1)
+	if err := sh.escribirHistorial(archivo); err != nil {
+		t.Fatal(err)
+	}
+	contenido, _ := os.ReadFile(archivo)
+	if esperado := "#" + strconv.FormatInt(hora.Unix(), 10) + "\nmake\n"; string(contenido) != esperado {
+		t.Errorf("archivo: esperado %q, obtenido %q", esperado, contenido)
+	}
+	sh.historial.Vaciar()
+	salida.Reset()
+	sh.Run(context.Background(), "history -r")
+	sh.Run(context.Background(), "history 1")
+	if esperado := "    1  2024-03-05 14:07:09 % make\n"; !strings.HasPrefix(salida.String(), esperado) {
+		t.Errorf("HISTTIMEFORMAT: esperado %q, obtenido %q", esperado, salida.String())
+	}
+
+	// PASO 3: Arriba y abajo recorren el historial y vuelven a la línea nueva
+	// (sin texto escrito, la búsqueda por el comienzo no filtra nada)
+	sh.historial.Vaciar()
+	sh.historial.Agregar("uno", hora, controlHistorial{}, -1)
+	sh.historial.Agregar("dos", hora, controlHistorial{}, -1)
+	teclas := map[string]string{
+		"\x1b[A\r":               "dos\n",
+		"\x1b[A\x1b[A\x1b[A\r":   "uno\n",
+		"tres\x10\x10\x0e\x0e\r": "tres\n",
+		"\x1b[A!\x1b[B\x1b[A\r":  "dos\n",
+	}
+	for entrada, esperado := range teclas {
+		if linea, err := editarTeclas(sh, entrada); err != nil || linea != esperado {
+			t.Errorf("%q: esperado %q, obtenido (%q, %v)", entrada, esperado, linea, err)
+		}
+	}
+	sh.opciones[optVi].Store(true)
+	if linea, _ := editarTeclas(sh, "x\x1bkkx\r"); linea != "no\n" {
+		t.Errorf("vi k: esperado %q, obtenido %q", "no\n", linea)
+	}
+}
+
+// TestBusquedaHistorial prueba la búsqueda incremental con Ctrl-R y Ctrl-S y
+// la búsqueda por el comienzo de la línea con las flechas.
+func TestBusquedaHistorial(t *testing.T) {
+	sh := NuevaShell()
+	sh.Stdout = io.Discard
+	for _, linea := range []string{"echo uno", "ls -l", "echo dos", "cd /tmp", "echo tres"} {
+		sh.agregarHistorial(linea)
+	}
+
+	casos := []struct {
+		nombre string
+		teclas string
+		linea  string
+	}{
+		{"Ctrl-R", "\x12ec\r", "echo tres\n"},
+		{"Ctrl-R repetido", "\x12echo\x12\x12\r", "echo uno\n"},
+		{"Ctrl-S vuelve", "\x12echo\x12\x12\x13\r", "echo dos\n"},
+		{"Más texto", "\x12ls -\r", "ls -l\n"},
+		{"Retroceso", "\x12lsx\x7f\r", "ls -l\n"},
+		{"Sin coincidencias", "\x12zz\r", "\n"},
+		{"Ctrl-G cancela", "abc\x12ec\x07d\r", "abcd\n"},
+		{"Editar lo encontrado", "\x12dos\x05!\r", "echo dos!\n"},
+		{"Arriba filtra", "ec\x1b[A\x1b[A\r", "echo dos\n"},
+		{"Abajo vuelve", "ec\x1b[A\x1b[A\x1b[B\r", "echo tres\n"},
+		{"Abajo hasta la línea nueva", "ec\x1b[A\x1b[B\r", "ec\n"},
+		{"Sin más coincidencias", "cd\x1b[A\x1b[A\r", "cd /tmp\n"},
+	}
+	for _, c := range casos {
+		linea, err := editarTeclas(sh, c.teclas)
+		if err != nil || linea != c.linea {
+			t.Errorf("%s: esperado %q, obtenido (%q, %v)", c.nombre, c.linea, linea, err)
+		}
+	}
+
+	// Ctrl-R dos veces repite la última búsqueda de la línea anterior
+	ed := sh.nuevoEditor(bufio.NewReader(strings.NewReader("\x12ls\r\x12\x12\r")))
+	ed.ancho = func() int { return 80 }
+	ed.editar()
+	if linea, err := ed.editar(); err != nil || linea != "ls -l\n" {
+		t.Errorf("repetir la búsqueda: obtenido (%q, %v)", linea, err)
+	}
+
+	// Durante la búsqueda el prompt la describe y lo encontrado se resalta
+	ed.iniciarLinea()
+	ed.atenderTecla(tecla{r: ctrl('R')})
+	ed.atenderTecla(tecla{r: 'd'})
+	if p, l := ed.textoPrompt(), ed.textoLinea(); p != "(reverse-i-search)`d': " || l != "c\x1b[7md\x1b[27m /tmp" {
+		t.Errorf("búsqueda: prompt %q, línea %q", p, l)
+	}
+	ed.atenderTecla(tecla{r: 'x'})
+	if p := ed.textoPrompt(); !strings.HasPrefix(p, "(failed reverse-i-search)") {
+		t.Errorf("búsqueda fallida: prompt %q", p)
+	}
+}
+
+// TestExpansionHistorial prueba la expansión del historial: eventos,
+// palabras, modificadores, :p, histverify y la shell no interactiva.
+func TestExpansionHistorial(t *testing.T) {
+	sh := NuevaShell()
+	var salida strings.Builder
+	sh.Stdout = &salida
+	sh.Stderr = io.Discard
+	for _, linea := range []string{"ls -l /tmp", "cp uno.txt /var/log/dos.tar.gz", "echo hola mundo"} {
+		sh.agregarHistorial(linea)
+	}
+
+	// Sin terminal (ej: printf ... | goshell) las líneas se ejecutan tal cual
+	// y no se guardan, como en bash
+	if linea, ejecutar := sh.lineaDelHistorial("echo hola!x !!\n"); !ejecutar || linea != "echo hola!x !!\n" || salida.Len() != 0 {
+		t.Errorf("No interactiva: (%q, %v), salida %q", linea, ejecutar, salida.String())
+	}
+	if cantidad := sh.historial.Cantidad(); cantidad != 3 {
+		t.Errorf("No interactiva: el historial tiene %d entradas", cantidad)
+	}
+	sh.interactiva = true
+
+	casos := []struct {
+		linea    string
+		esperado string // "" si la expansión falla
+	}{
+		{"!!", "echo hola mundo"},
+		{"!1", "ls -l /tmp"},
+		{"!-2", "cp uno.txt /var/log/dos.tar.gz"},
+		{"!ls", "ls -l /tmp"},
+		{"!?uno?", "cp uno.txt /var/log/dos.tar.gz"},
+		{"echo !$", "echo mundo"},
+		{"echo !^ !*", "echo hola hola mundo"},
+		{"echo !cp:0 !cp:1-", "echo cp uno.txt"},
+		{"echo !cp:2-", ""},
+		{"echo !cp:1-2", "echo uno.txt /var/log/dos.tar.gz"},
+		{"echo !cp:-1", "echo cp uno.txt"},
+		{"echo !cp:2*", "echo /var/log/dos.tar.gz"},
+		{"cd !cp:$:h", "cd /var/log"},
+		{"echo !cp:$:t:r", "echo dos.tar"},
+		{"echo !cp:$:e", "echo .gz"},
+		{"!!:s/hola/chau/", "echo chau mundo"},
+		{"!!:gs/o/0/", "ech0 h0la mund0"},
+		{"!!:s/mundo/& & &/", "echo hola mundo mundo mundo"},
+		{"^hola^adiós", "echo adiós mundo"},
+		{"!!:&", "echo adiós mundo"},
+		{"echo hola! \\!! a != b", "echo hola! !! a != b"},
+		{"echo 'hola!mundo'", "echo 'hola!mundo'"},
+		{"git commit -m 'fix!'", "git commit -m 'fix!'"},
+		{"echo 'a !! b' !!", "echo 'a !! b' echo hola mundo"},
+		{"echo \"it's !!\"", "echo \"it's echo hola mundo\""},
+		{"echo \\' !!", "echo \\' echo hola mundo"},
+		{"!nada", ""},
+		{"!9", ""},
+		{"echo !cp:5", ""},
+		{"!!:s/nada/x/", ""},
+		{"!!:z", ""},
+	}
+	for _, c := range casos {
+		obtenido, _, _, err := sh.expandirHistorial(c.linea)
+		if (err != nil) != (c.esperado == "") || (err == nil && obtenido != c.esperado) {
+			t.Errorf("%q: esperado %q, obtenido (%q, %v)", c.linea, c.esperado, obtenido, err)
+		}
+	}
+
+	// Un '!' escapado o entre comillas simples no es una expansión: la línea
+	// no se muestra
+	for _, linea := range []string{"echo \\!x", "echo 'a!b'"} {
+		if _, expandida, _, err := sh.expandirHistorial(linea); expandida || err != nil {
+			t.Errorf("%q: expandida %v, error %v", linea, expandida, err)
+		}
+	}
+
+	// La línea expandida se muestra, se guarda en el historial y, con :p,
+	// no se ejecuta
+	linea, ejecutar := sh.lineaDelHistorial("!ls:p\n")
+	if ejecutar || linea != "ls -l /tmp\n" || salida.String() != "ls -l /tmp\n" {
+		t.Errorf(":p: (%q, %v), salida %q", linea, ejecutar, salida.String())
+	}
+	if ultima := sh.historial.Entrada(sh.historial.Cantidad() - 1); ultima != "ls -l /tmp" {
+		t.Errorf(":p: última entrada %q", ultima)
+	}
+
+	// Con histverify la línea vuelve al editor sin ejecutarse
+	sh.editor = sh.nuevoEditor(bufio.NewReader(strings.NewReader("\r")))
+	sh.Run(context.Background(), "shopt -s histverify")
+	if _, ejecutar := sh.lineaDelHistorial("!!:s/-l/-a/\n"); ejecutar || sh.editor.pendiente != "ls -a /tmp" {
+		t.Errorf("histverify: %v, pendiente %q", ejecutar, sh.editor.pendiente)
+	}
+	sh.editor.ancho = func() int { return 80 }
+	if linea, err := sh.editor.editar(); err != nil || linea != "ls -a /tmp\n" {
+		t.Errorf("histverify: editor (%q, %v)", linea, err)
+	}
+}
+
+// TestCompletado prueba el completado con Tab de comandos y rutas.
+func TestCompletado(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "archivo.txt"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, ".oculto"), nil, 0o644)
+	os.Mkdir(filepath.Join(dir, "artículos"), 0o755)
+
+	sh := NuevaShell()
+	sh.Env = []string{"PATH=", "HOME=" + dir}
+	sh.Dir = dir
+
+	casos := []struct {
+		linea    string
+		inicio   int
+		opciones []string
+	}{
+		{"cat ar", 4, []string{"archivo.txt", "artículos/"}},
+		{"cat .", 4, []string{".oculto"}},
+		{"cat ~/arc", 4, []string{"~/archivo.txt"}},
+		{"hel", 0, []string{"help"}},
+		{"ls | hel", 5, []string{"help"}},
+		{"./art", 0, []string{"./artículos/"}},
+	}
+	for _, c := range casos {
+		inicio, opciones := sh.completarPalabra([]rune(c.linea), len([]rune(c.linea)))
+		if inicio != c.inicio || !equal(opciones, c.opciones) {
+			t.Errorf("%q: esperado (%d, %v), obtenido (%d, %v)", c.linea, c.inicio, c.opciones, inicio, opciones)
+		}
+	}
+
+	// En el editor, Tab completa la parte común y la opción única
+	if linea, _ := editarTeclas(sh, "cat arc\t\r"); linea != "cat archivo.txt \n" {
+		t.Errorf("Tab con una opción: %q", linea)
+	}
+	if linea, _ := editarTeclas(sh, "cd a\t\tt\t\r"); linea != "cd artículos/\n" {
+		t.Errorf("Tab con varias opciones: %q", linea)
+	}
+}
@@ -0,0 +1,139 @@
+// Módulo sugerencias: Atiende los comandos que no existen. Muestra un mensaje
+// limpio con sugerencias de nombres parecidos o delega en el comando interno
+// command_not_found_handle, si se registró uno
+package goshell
+
+import (
+	"errors"        // Para reconocer el programa no encontrado
+	"fmt"           // Para mostrar el mensaje y las sugerencias
+	"os/exec"       // Para el error de programa no encontrado
+	"path/filepath" // Para recorrer los directorios del PATH
+	"sort"          // Para ordenar las sugerencias
+	"strings"       // Para reconocer rutas en el nombre del comando
+	"unicode/utf8"  // Para medir los nombres en caracteres
+)
+
+// manejadorNoEncontrado es el nombre del comando interno que, si está
+// registrado, se ejecuta en lugar de un comando que no existe. Como en bash,
+// recibe el nombre del comando y sus argumentos, y su estado es el del comando.
+// La shell no tiene funciones, por lo que se define desde Go:
+//
+//	sh.Builtins().Registrar(goshell.NuevoComandoInterno("command_not_found_handle", ...))
+const manejadorNoEncontrado = "command_not_found_handle"
+
+// maximoSugerencias limita cuántos nombres parecidos se sugieren
+const maximoSugerencias = 5
+
+// comandoNoEncontrado devuelve el comando interno que atiende un comando que
+// no es interno ni está en el PATH, o nil si el programa existe. Se ejecuta
+// como una etapa más de la tubería, así que sus mensajes respetan las
+// redirecciones del comando (ej: "gti 2> errores.txt").
+//
+// Parámetros:
+//   - nombre: nombre del comando, ya expandido
+//
+// Retorna:
+//   - Builtin: el comando que informa el error (o llama al manejador), o nil
+func (sh *Shell) comandoNoEncontrado(nombre string) Builtin {
+	if _, err := sh.buscarPrograma(nombre); !errors.Is(err, exec.ErrNotFound) {
+		return nil // Existe, o el error es otro y lo informará al iniciarlo
+	}
+
+	return NuevoComandoInterno(nombre, "", "", func(sh *Shell, args []string, es EntradaSalida) error {
+		if manejador, ok := sh.builtins.Buscar(manejadorNoEncontrado); ok {
+			return manejador.Ejecutar(sh, append([]string{nombre}, args...), es)
+		}
+
+		fmt.Fprintf(es.Error, "goshell: %s: command not found\n", nombre)
+		if sugerencias := sh.sugerencias(nombre); len(sugerencias) > 0 {
+			fmt.Fprintln(es.Error, "¿Quisiste decir alguno de estos comandos?")
+			for _, s := range sugerencias {
+				fmt.Fprintf(es.Error, "    %s\n", s)
+			}
+		}
+		return EstadoSalida(127)
+	})
+}
+
+// sugerencias busca entre los comandos internos y los programas del PATH los
+// nombres más parecidos al comando escrito, según la distancia de edición.
+// Para nombres cortos solo se acepta un error (una letra de más, de menos,
+// cambiada o dos letras intercambiadas); para el resto, hasta dos.
+//
+// Parámetros:
+//   - nombre: comando que no se encontró
+//
+// Retorna:
+//   - []string: hasta maximoSugerencias nombres, los más parecidos primero
+func (sh *Shell) sugerencias(nombre string) []string {
+	// Los nombres con separadores de directorio son rutas, no comandos
+	if nombre == "" || strings.ContainsAny(nombre, `/`+string(filepath.Separator)) {
+		return nil
+	}
+
+	limite := 1
+	if utf8.RuneCountInString(nombre) > 4 {
+		limite = 2
+	}
+
+	type candidato struct {
+		nombre    string
+		distancia int
+	}
+	var candidatos []candidato
+	vistos := map[string]bool{}
+	for _, c := range append(sh.builtins.Nombres(), sh.programasPath()...) {
+		if vistos[c] || c == manejadorNoEncontrado {
+			continue
+		}
+		vistos[c] = true
+		if d := distanciaEdicion(nombre, c); d <= limite {
+			candidatos = append(candidatos, candidato{c, d})
+		}
+	}
+
+	sort.Slice(candidatos, func(i, j int) bool {
+		if candidatos[i].distancia != candidatos[j].distancia {
+			return candidatos[i].distancia < candidatos[j].distancia
+		}
+		return candidatos[i].nombre < candidatos[j].nombre
+	})
+	var nombres []string
+	for i := 0; i < len(candidatos) && i < maximoSugerencias; i++ {
+		nombres = append(nombres, candidatos[i].nombre)
+	}
+	return nombres
+}
+
+// distanciaEdicion calcula la distancia de Damerau-Levenshtein restringida
+// entre dos palabras: cuántas inserciones, eliminaciones, sustituciones o
+// intercambios de dos caracteres vecinos hacen falta para pasar de una a otra.
+// Así "gti" está a distancia 1 de "git".
+func distanciaEdicion(a, b string) int {
+	x, y := []rune(a), []rune(b)
+
+	// d[i][j] es la distancia entre los primeros i caracteres de x y los
+	// primeros j de y
+	d := make([][]int, len(x)+1)
+	for i := range d {
+		d[i] = make([]int, len(y)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(x); i++ {
+		for j := 1; j <= len(y); j++ {
+			costo := 1
+			if x[i-1] == y[j-1] {
+				costo = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+costo)
+			if i > 1 && j > 1 && x[i-1] == y[j-2] && x[i-2] == y[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(x)][len(y)]
+}
@@ -0,0 +1,12 @@
+//go:build darwin || freebsd || netbsd || openbsd || dragonfly
+
+// Módulo terminal (macOS y BSD): Peticiones ioctl para leer y fijar el modo
+// de la terminal
+package goshell
+
+import "syscall" // Para los números de las peticiones ioctl
+
+const (
+	ioctlLeerTermios  = syscall.TIOCGETA
+	ioctlFijarTermios = syscall.TIOCSETA
+)
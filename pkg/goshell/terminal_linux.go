@@ -0,0 +1,12 @@
+//go:build linux
+
+// Módulo terminal (Linux): Peticiones ioctl para leer y fijar el modo de la
+// terminal
+package goshell
+
+import "syscall" // Para los números de las peticiones ioctl
+
+const (
+	ioctlLeerTermios  = syscall.TCGETS
+	ioctlFijarTermios = syscall.TCSETS
+)
@@ -0,0 +1,21 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+// Módulo terminal (otros sistemas): Sin modo crudo, el editor de línea no
+// está disponible y la shell lee líneas completas, con la edición de la
+// propia terminal
+package goshell
+
+import (
+	"errors" // Para informar que el modo crudo no está soportado
+	"os"     // Para mantener la misma firma que la versión Unix
+)
+
+// modoCrudo no está soportado en este sistema
+func modoCrudo(f *os.File) (func() error, error) {
+	return nil, errors.New("modo crudo no soportado en este sistema")
+}
+
+// anchoTerminal devuelve un ancho fijo de 80 columnas
+func anchoTerminal(f *os.File) int {
+	return 80
+}
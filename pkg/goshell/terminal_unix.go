@@ -0,0 +1,64 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+// Módulo terminal (Unix): Pone la terminal en modo crudo mientras se edita
+// una línea, para recibir cada tecla en cuanto se pulsa y sin eco, y consulta
+// el ancho de la ventana
+package goshell
+
+import (
+	"os"      // Para el descriptor de la terminal
+	"syscall" // Para las llamadas ioctl sobre la terminal
+	"unsafe"  // Para pasar las estructuras a ioctl
+)
+
+// ioctl ejecuta una llamada ioctl sobre un descriptor de archivo
+func ioctl(fd uintptr, peticion uintptr, dato unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, peticion, uintptr(dato)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// modoCrudo pone la terminal en modo crudo: sin modo canónico (cada tecla se
+// lee en cuanto se pulsa), sin eco, sin señales de teclado (Ctrl-C llega como
+// una tecla más) y sin traducir el retorno de carro. La salida se sigue
+// procesando, así que "\n" sigue avanzando a una línea nueva.
+//
+// Parámetros:
+//   - f: terminal de la que lee la shell
+//
+// Retorna:
+//   - func() error: función que devuelve la terminal a su modo original
+//   - error: si f no es una terminal o no se pudo cambiar el modo
+func modoCrudo(f *os.File) (func() error, error) {
+	var original syscall.Termios
+	if err := ioctl(f.Fd(), ioctlLeerTermios, unsafe.Pointer(&original)); err != nil {
+		return nil, err
+	}
+
+	crudo := original
+	crudo.Iflag &^= syscall.BRKINT | syscall.ICRNL | syscall.INPCK | syscall.ISTRIP | syscall.IXON
+	crudo.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN | syscall.ISIG
+	crudo.Cflag |= syscall.CS8
+	crudo.Cc[syscall.VMIN] = 1
+	crudo.Cc[syscall.VTIME] = 0
+	if err := ioctl(f.Fd(), ioctlFijarTermios, unsafe.Pointer(&crudo)); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return ioctl(f.Fd(), ioctlFijarTermios, unsafe.Pointer(&original))
+	}, nil
+}
+
+// anchoTerminal devuelve la cantidad de columnas de la terminal, o 80 si no
+// puede consultarse
+func anchoTerminal(f *os.File) int {
+	var tamano struct {
+		filas, columnas, x, y uint16
+	}
+	if err := ioctl(f.Fd(), syscall.TIOCGWINSZ, unsafe.Pointer(&tamano)); err != nil || tamano.columnas == 0 {
+		return 80
+	}
+	return int(tamano.columnas)
+}
@@ -0,0 +1,583 @@
+// Módulo trabajos: Lleva el registro de los procesos lanzados en segundo plano
+// Permite informar al usuario cuando un trabajo termina y con qué estado lo hizo
+package goshell
+
+import (
+	"errors"  // Para inspeccionar el tipo de error devuelto por cmd.Wait
+	"fmt"     // Para formatear las notificaciones de los trabajos
+	"io"      // Para escribir las notificaciones en cualquier destino
+	"os/exec" // Para reconocer errores de tipo *exec.ExitError
+	"strconv" // Para interpretar números de trabajo y PIDs
+	"strings" // Para dar formato al nombre de las señales
+	"sync"    // Para proteger la tabla del acceso concurrente de las goroutines
+	"syscall" // Para obtener la señal que terminó un proceso
+)
+
+// Trabajo representa un comando lanzado en segundo plano con el sufijo &.
+type Trabajo struct {
+	ID      int    // Número de trabajo que ve el usuario (el N de [N])
+	PID     int    // Process ID del proceso hijo
+	Comando string // Línea de comando tal como se muestra en las notificaciones
+	Grupo   bool   // true si el proceso encabeza su propio grupo (PGID == PID)
+
+	terminado  bool               // true cuando cmd.Wait() ya retornó
+	estado     syscall.WaitStatus // Estado de salida devuelto por el sistema
+	codigo     int                // Código de salida al estilo de la shell ($?)
+	notificado bool               // true cuando ya se informó la terminación
+	sinHup     bool               // true tras "disown -h": no recibe SIGHUP al salir
+}
+
+// TablaTrabajos guarda los trabajos en segundo plano que la shell conoce.
+// Es accedida tanto por el bucle REPL como por las goroutines que esperan
+// a cada proceso, por eso todas sus operaciones se protegen con un mutex.
+type TablaTrabajos struct {
+	mu       sync.Mutex
+	cambio   *sync.Cond // Se señala cada vez que un trabajo termina (usado por wait)
+	trabajos []*Trabajo // Ordenados del más antiguo al más reciente
+}
+
+// Agregar registra un nuevo trabajo y le asigna el siguiente número disponible.
+// Igual que en bash, el número es uno más que el mayor número en uso, por lo
+// que la numeración vuelve a empezar en 1 cuando la tabla queda vacía.
+//
+// Parámetros:
+//   - pid: Process ID del proceso hijo
+//   - grupo: true si el proceso se lanzó en su propio grupo de procesos
+//   - comando: texto del comando para mostrar en las notificaciones
+//
+// Retorna:
+//   - *Trabajo: el trabajo recién registrado
+func (tt *TablaTrabajos) Agregar(pid int, grupo bool, comando string) *Trabajo {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	id := 1
+	for _, t := range tt.trabajos {
+		if t.ID >= id {
+			id = t.ID + 1
+		}
+	}
+
+	t := &Trabajo{ID: id, PID: pid, Comando: comando, Grupo: grupo}
+	tt.trabajos = append(tt.trabajos, t)
+	return t
+}
+
+// Finalizar marca un trabajo como terminado a partir del error devuelto por cmd.Wait().
+//
+// Parámetros:
+//   - t: trabajo que terminó
+//   - err: resultado de cmd.Wait() (nil si el proceso salió con código 0)
+func (tt *TablaTrabajos) Finalizar(t *Trabajo, err error) {
+	estado, codigo := estadoDeEspera(err)
+
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	t.terminado = true
+	t.estado = estado
+	t.codigo = codigo
+
+	// Despertar a los comandos wait que estén esperando algún trabajo
+	tt.condicion().Broadcast()
+}
+
+// Buscar encuentra un trabajo a partir de una especificación de trabajo o un PID.
+//
+// Especificaciones soportadas (igual que en bash):
+//   - %N: el trabajo número N
+//   - %+ o %%: el trabajo actual (el más reciente)
+//   - %-: el trabajo anterior
+//   - %texto: el trabajo cuyo comando empieza con "texto"
+//   - %?texto: el trabajo cuyo comando contiene "texto"
+//   - N (sin %): el trabajo cuyo proceso tiene PID N
+//
+// Parámetros:
+//   - espec: especificación de trabajo o PID escrito por el usuario
+//
+// Retorna:
+//   - *Trabajo: el trabajo encontrado
+//   - error: si la especificación es inválida, ambigua o no existe el trabajo
+func (tt *TablaTrabajos) Buscar(espec string) (*Trabajo, error) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	// Sin %: se interpreta como PID de un proceso hijo
+	if !strings.HasPrefix(espec, "%") {
+		pid, err := strconv.Atoi(espec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: no es un PID ni una especificación de trabajo válida", espec)
+		}
+		for _, t := range tt.trabajos {
+			if t.PID == pid {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("pid %d no es un hijo de esta shell", pid)
+	}
+
+	n := len(tt.trabajos)
+	cuerpo := espec[1:]
+	switch {
+	case cuerpo == "" || cuerpo == "+" || cuerpo == "%":
+		if n >= 1 {
+			return tt.trabajos[n-1], nil
+		}
+	case cuerpo == "-":
+		if n >= 2 {
+			return tt.trabajos[n-2], nil
+		}
+		if n == 1 {
+			return tt.trabajos[0], nil
+		}
+	default:
+		if id, err := strconv.Atoi(cuerpo); err == nil {
+			for _, t := range tt.trabajos {
+				if t.ID == id {
+					return t, nil
+				}
+			}
+			break
+		}
+
+		// Búsqueda por texto: %texto (prefijo) o %?texto (contiene)
+		coincide := func(t *Trabajo) bool { return strings.HasPrefix(t.Comando, cuerpo) }
+		if strings.HasPrefix(cuerpo, "?") {
+			texto := cuerpo[1:]
+			coincide = func(t *Trabajo) bool { return strings.Contains(t.Comando, texto) }
+		}
+		var encontrado *Trabajo
+		for _, t := range tt.trabajos {
+			if coincide(t) {
+				if encontrado != nil {
+					return nil, fmt.Errorf("%s: especificación de trabajo ambigua", espec)
+				}
+				encontrado = t
+			}
+		}
+		if encontrado != nil {
+			return encontrado, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: no existe ese trabajo", espec)
+}
+
+// Esperar bloquea hasta que el trabajo termine y lo retira de la tabla, ya que
+// su estado se entrega directamente a quien lo esperó y no hace falta notificarlo.
+//
+// Parámetros:
+//   - t: trabajo a esperar
+//
+// Retorna:
+//   - int: código de salida del trabajo
+func (tt *TablaTrabajos) Esperar(t *Trabajo) int {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	for !t.terminado {
+		tt.condicion().Wait()
+	}
+	t.notificado = true
+	tt.limpiar()
+	return t.codigo
+}
+
+// EsperarCualquiera bloquea hasta que termine alguno de los trabajos dados
+// (o cualquier trabajo de la tabla si la lista está vacía). Si alguno ya había
+// terminado sin ser notificado, retorna de inmediato. Implementa "wait -n".
+//
+// Parámetros:
+//   - candidatos: trabajos entre los que se espera; vacío significa todos
+//
+// Retorna:
+//   - *Trabajo: el trabajo que terminó (nil si no había trabajos que esperar)
+//   - int: su código de salida
+func (tt *TablaTrabajos) EsperarCualquiera(candidatos []*Trabajo) (*Trabajo, int) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	for {
+		lista := candidatos
+		if len(lista) == 0 {
+			lista = tt.trabajos
+		}
+
+		pendientes := 0
+		for _, t := range lista {
+			if t.notificado {
+				continue // Ya fue esperado o notificado
+			}
+			if t.terminado {
+				t.notificado = true
+				tt.limpiar()
+				return t, t.codigo
+			}
+			pendientes++
+		}
+		if pendientes == 0 {
+			return nil, 0
+		}
+		tt.condicion().Wait()
+	}
+}
+
+// Desligar retira un trabajo de la tabla sin esperarlo (disown). El proceso
+// sigue ejecutándose, pero la shell ya no lo notifica ni le envía SIGHUP.
+func (tt *TablaTrabajos) Desligar(t *Trabajo) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	t.notificado = true
+	tt.limpiar()
+}
+
+// MarcarSinHup deja el trabajo en la tabla pero lo excluye del SIGHUP que la
+// shell envía a sus trabajos al terminar (disown -h, semántica de nohup).
+func (tt *TablaTrabajos) MarcarSinHup(t *Trabajo) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	t.sinHup = true
+}
+
+// Terminado indica si el proceso del trabajo ya finalizó
+func (tt *TablaTrabajos) Terminado(t *Trabajo) bool {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	return t.terminado
+}
+
+// EnEjecucion devuelve la cantidad de trabajos que todavía no terminaron
+func (tt *TablaTrabajos) EnEjecucion() int {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	n := 0
+	for _, t := range tt.trabajos {
+		if !t.terminado {
+			n++
+		}
+	}
+	return n
+}
+
+// EnviarHup envía SIGHUP a todos los trabajos en ejecución que no hayan sido
+// excluidos con "disown -h". Se usa cuando la shell termina, ya sea con exit o
+// porque recibió SIGHUP (por ejemplo, al cerrarse la terminal). Después de
+// SIGHUP se envía SIGCONT para que un trabajo detenido pueda procesarla.
+func (tt *TablaTrabajos) EnviarHup() {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	for _, t := range tt.trabajos {
+		if t.terminado || t.sinHup {
+			continue
+		}
+		enviarSenal(t.PID, t.Grupo, syscall.SIGHUP)
+		enviarSenal(t.PID, t.Grupo, senalContinuar)
+	}
+}
+
+// Todos devuelve una copia de la lista de trabajos registrados
+func (tt *TablaTrabajos) Todos() []*Trabajo {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	return append([]*Trabajo(nil), tt.trabajos...)
+}
+
+// condicion devuelve la variable de condición de la tabla, creándola la primera
+// vez. Debe llamarse con el mutex tomado.
+func (tt *TablaTrabajos) condicion() *sync.Cond {
+	if tt.cambio == nil {
+		tt.cambio = sync.NewCond(&tt.mu)
+	}
+	return tt.cambio
+}
+
+// NotificarTerminados escribe una línea por cada trabajo terminado que aún no
+// se haya informado y lo elimina de la tabla. El bucle REPL la llama justo antes
+// de mostrar el prompt, igual que hace bash.
+//
+// Formato de cada línea:
+//   - "[1]+ Done  sleep 5"
+//   - "[2]- Exit 3  make"
+//
+// Parámetros:
+//   - w: destino de las notificaciones (normalmente la salida de la shell)
+//
+// Retorna:
+//   - int: cantidad de trabajos notificados
+func (tt *TablaTrabajos) NotificarTerminados(w io.Writer) int {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	notificados := 0
+	for _, t := range tt.trabajos {
+		if t.terminado && !t.notificado {
+			fmt.Fprintln(w, tt.formatear(t))
+			t.notificado = true
+			notificados++
+		}
+	}
+	tt.limpiar()
+	return notificados
+}
+
+// Notificar escribe la línea de estado de un único trabajo y lo elimina de la
+// tabla. Se usa para la notificación inmediata de "set -b".
+//
+// Parámetros:
+//   - w: destino de la notificación
+//   - t: trabajo a notificar
+//
+// Retorna:
+//   - bool: false si el trabajo ya había sido notificado
+func (tt *TablaTrabajos) Notificar(w io.Writer, t *Trabajo) bool {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	if t.notificado {
+		return false
+	}
+	fmt.Fprintln(w, tt.formatear(t))
+	t.notificado = true
+	tt.limpiar()
+	return true
+}
+
+// formatear construye la línea de estado de un trabajo. Debe llamarse con el
+// mutex tomado, ya que la marca (+ o -) depende del resto de la tabla.
+func (tt *TablaTrabajos) formatear(t *Trabajo) string {
+	return fmt.Sprintf("[%d]%c %s  %s", t.ID, tt.marca(t), t.descripcionEstado(), t.Comando)
+}
+
+// marca devuelve '+' para el trabajo actual (el más reciente), '-' para el
+// anterior y ' ' para el resto, siguiendo la convención de bash.
+func (tt *TablaTrabajos) marca(t *Trabajo) rune {
+	n := len(tt.trabajos)
+	switch {
+	case n >= 1 && tt.trabajos[n-1] == t:
+		return '+'
+	case n >= 2 && tt.trabajos[n-2] == t:
+		return '-'
+	default:
+		return ' '
+	}
+}
+
+// limpiar elimina de la tabla los trabajos ya notificados.
+// Debe llamarse con el mutex tomado.
+func (tt *TablaTrabajos) limpiar() {
+	vivos := tt.trabajos[:0]
+	for _, t := range tt.trabajos {
+		if !t.notificado {
+			vivos = append(vivos, t)
+		}
+	}
+	tt.trabajos = vivos
+}
+
+// descripcionEstado devuelve el texto de estado que usa bash para un trabajo:
+// "Running", "Done", "Exit N" o el nombre de la señal que lo terminó.
+func (t *Trabajo) descripcionEstado() string {
+	switch {
+	case !t.terminado:
+		return "Running"
+	case t.estado.Signaled():
+		return nombreSenal(t.estado.Signal())
+	case t.codigo == 0:
+		return "Done"
+	default:
+		return fmt.Sprintf("Exit %d", t.codigo)
+	}
+}
+
+// estadoDeEspera traduce el error devuelto por cmd.Wait() al estado del sistema
+// y al código de salida que usa la shell. Si el proceso terminó por una señal,
+// el código es 128 + número de señal, como en bash.
+//
+// Parámetros:
+//   - err: resultado de cmd.Wait() o cmd.Run()
+//
+// Retorna:
+//   - syscall.WaitStatus: estado de salida del proceso (vacío si no está disponible)
+//   - int: código de salida al estilo de la shell
+func estadoDeEspera(err error) (syscall.WaitStatus, int) {
+	var estado syscall.WaitStatus
+	if err == nil {
+		return estado, 0
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		// El proceso ni siquiera pudo esperarse correctamente
+		return estado, 1
+	}
+
+	if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+		estado = ws
+		if ws.Signaled() {
+			return estado, 128 + int(ws.Signal())
+		}
+	}
+	return estado, exitErr.ExitCode()
+}
+
+// nombreSenal devuelve el nombre legible de una señal con la primera letra en
+// mayúscula (ej: "Terminated", "Killed"), que es como bash las muestra.
+func nombreSenal(s syscall.Signal) string {
+	nombre := s.String()
+	if nombre == "" {
+		return "Signal"
+	}
+	return strings.ToUpper(nombre[:1]) + nombre[1:]
+}
+
+// EstadoSalida es el error que devuelven los comandos internos para informar un
+// código de salida distinto de cero sin un mensaje adicional (ej: wait).
+type EstadoSalida int
+
+// Error implementa la interfaz error con el mismo texto que usa os/exec
+func (e EstadoSalida) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+// resultadoEstado convierte un código de salida en el error que devuelven los
+// comandos internos: nil para 0 y EstadoSalida para cualquier otro valor.
+func resultadoEstado(codigo int) error {
+	if codigo == 0 {
+		return nil
+	}
+	return EstadoSalida(codigo)
+}
+
+// ejecutarWait implementa el comando interno 'wait' para esperar trabajos en
+// segundo plano.
+//
+// Formas soportadas:
+//   - wait: espera a que terminen todos los trabajos, retorna 0
+//   - wait %1 %2 / wait 1234: espera los trabajos indicados, retorna el estado del último
+//   - wait -n [trabajos]: espera al primero que termine y retorna su estado
+//
+// Parámetros:
+//   - sh: shell cuyos trabajos se esperan
+//   - args: slice de argumentos del comando wait
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: nil si el trabajo esperado salió con 0, EstadoSalida con su código
+//     en caso contrario, o un error si alguna especificación es inválida
+func ejecutarWait(sh *Shell, args []string, es EntradaSalida) error {
+	siguiente := false
+	if len(args) > 0 && args[0] == "-n" {
+		siguiente = true
+		args = args[1:]
+	}
+
+	// Resolver todas las especificaciones antes de bloquear
+	var trabajos []*Trabajo
+	for _, espec := range args {
+		t, err := sh.trabajos.Buscar(espec)
+		if err != nil {
+			return fmt.Errorf("wait: %v", err)
+		}
+		trabajos = append(trabajos, t)
+	}
+
+	// wait -n: el primero de los trabajos en terminar
+	if siguiente {
+		t, codigo := sh.trabajos.EsperarCualquiera(trabajos)
+		if t == nil {
+			return EstadoSalida(127) // No había trabajos que esperar
+		}
+		return resultadoEstado(codigo)
+	}
+
+	// wait sin argumentos: todos los trabajos, siempre retorna 0
+	if len(trabajos) == 0 {
+		for _, t := range sh.trabajos.Todos() {
+			sh.trabajos.Esperar(t)
+		}
+		return nil
+	}
+
+	// wait con trabajos: el estado es el del último trabajo indicado
+	codigo := 0
+	for _, t := range trabajos {
+		codigo = sh.trabajos.Esperar(t)
+	}
+	return resultadoEstado(codigo)
+}
+
+// ejecutarDisown implementa el comando interno 'disown' para desligar trabajos
+// de la shell.
+//
+// Formas soportadas:
+//   - disown [trabajos]: retira los trabajos de la tabla (por defecto el actual)
+//   - disown -h [trabajos]: los mantiene en la tabla pero sin enviarles SIGHUP al salir
+//   - disown -a: aplica a todos los trabajos
+//   - disown -r: aplica solo a los trabajos en ejecución
+//
+// Parámetros:
+//   - sh: shell cuyos trabajos se desligan
+//   - args: slice de argumentos del comando disown
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: nil si todos los trabajos existían, error en caso contrario
+func ejecutarDisown(sh *Shell, args []string, es EntradaSalida) error {
+	soloHup, todos, soloEjecucion := false, false, false
+
+	// PASO 1: Procesar las opciones (pueden agruparse, ej: -ah)
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+		for _, letra := range args[0][1:] {
+			switch letra {
+			case 'h':
+				soloHup = true
+			case 'a':
+				todos = true
+			case 'r':
+				soloEjecucion = true
+			default:
+				return fmt.Errorf("disown: -%c: opción inválida", letra)
+			}
+		}
+		args = args[1:]
+	}
+
+	// PASO 2: Determinar los trabajos afectados
+	var trabajos []*Trabajo
+	switch {
+	case len(args) > 0:
+		for _, espec := range args {
+			t, err := sh.trabajos.Buscar(espec)
+			if err != nil {
+				return fmt.Errorf("disown: %v", err)
+			}
+			trabajos = append(trabajos, t)
+		}
+	case todos || soloEjecucion:
+		trabajos = sh.trabajos.Todos()
+	default:
+		t, err := sh.trabajos.Buscar("%+")
+		if err != nil {
+			return errors.New("disown: no hay trabajo actual")
+		}
+		trabajos = []*Trabajo{t}
+	}
+
+	// PASO 3: Desligar o marcar cada trabajo
+	for _, t := range trabajos {
+		if soloEjecucion && sh.trabajos.Terminado(t) {
+			continue
+		}
+		if soloHup {
+			sh.trabajos.MarcarSinHup(t)
+		} else {
+			sh.trabajos.Desligar(t)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+// Módulo trampas: Implementa el comando interno trap
+// Permite asociar comandos a señales (INT, TERM, ...) y a pseudo-señales de la
+// shell (EXIT, ERR, DEBUG, RETURN). Los manejadores nunca se ejecutan dentro de
+// la goroutine que recibe la señal: la señal queda encolada en el canal de
+// señales de la shell y el bucle REPL la atiende entre un comando y el siguiente
+package goshell
+
+import (
+	"errors"  // Para reconocer el error centinela de exit
+	"fmt"     // Para mostrar las trampas y los errores
+	"io"      // Para escribir las trampas en la salida del comando
+	"sort"    // Para listar las trampas en un orden estable
+	"strconv" // Para interpretar señales numéricas
+	"strings" // Para reconstruir el comando de la trampa
+	"syscall" // Para el tipo syscall.Signal
+)
+
+// Pseudo-señales: no las envía el sistema operativo sino la propia shell
+const (
+	trampaSalida  = "EXIT"   // Al terminar la shell
+	trampaError   = "ERR"    // Después de un comando que termina con estado distinto de 0
+	trampaDepurar = "DEBUG"  // Antes de ejecutar cada comando
+	trampaRetorno = "RETURN" // Al terminar un script ejecutado por la shell
+)
+
+// ejecutarTrap implementa el comando interno 'trap'.
+//
+// Formas soportadas:
+//   - trap 'comando' SEÑAL...: ejecuta el comando cuando llegue alguna de las señales
+//   - trap '' SEÑAL...: ignora las señales
+//   - trap - SEÑAL... / trap SEÑAL: restaura el comportamiento por defecto
+//   - trap / trap -p [SEÑAL...]: muestra las trampas en formato reutilizable
+//   - trap -l: lista los nombres de las señales
+//
+// Como la shell no interpreta comillas, el comando de la trampa son todos los
+// argumentos anteriores a las señales, unidos por espacios y sin las comillas
+// exteriores: trap 'rm -rf /tmp/x' EXIT INT → comando "rm -rf /tmp/x".
+//
+// Parámetros:
+//   - sh: shell en la que se definen las trampas
+//   - args: slice de argumentos del comando trap
+//   - es: flujos estándar del comando
+//
+// Retorna:
+//   - error: nil si la operación fue exitosa, error si alguna señal es inválida
+func ejecutarTrap(sh *Shell, args []string, es EntradaSalida) error {
+	// PASO 1: Formas de consulta
+	if len(args) == 0 {
+		return sh.mostrarTrampas(nil, es.Salida)
+	}
+	switch args[0] {
+	case "-p":
+		return sh.mostrarTrampas(args[1:], es.Salida)
+	case "-l":
+		return listarSenales(nil, es.Salida)
+	case "--":
+		args = args[1:]
+	}
+
+	// PASO 2: Separar el comando de las señales, recorriendo desde el final
+	inicio := len(args)
+	for inicio > 0 {
+		if _, err := normalizarTrampa(args[inicio-1]); err != nil {
+			break
+		}
+		inicio--
+	}
+	senales := args[inicio:]
+	if len(senales) == 0 {
+		return fmt.Errorf("trap: %s: especificación de señal inválida", args[len(args)-1])
+	}
+
+	// Con un único operando que es una señal, bash lo trata como "trap - SEÑAL"
+	restaurar := inicio == 0 && len(senales) == 1
+	if inicio == 1 && args[0] == "-" {
+		restaurar = true
+	}
+	comando := quitarComillas(strings.Join(args[:inicio], " "))
+	if inicio == 0 && len(senales) > 1 {
+		// "trap INT TERM": el primer argumento es el comando, no una señal
+		comando = senales[0]
+		senales = senales[1:]
+	}
+
+	// PASO 3: Aplicar la trampa a cada señal
+	for _, texto := range senales {
+		nombre, _ := normalizarTrampa(texto)
+		if nombre == "KILL" || nombre == "STOP" {
+			// Se rechaza antes de tocar las trampas: no debe quedar definida
+			return fmt.Errorf("trap: %s: esta señal no puede atraparse", texto)
+		}
+		if restaurar {
+			delete(sh.trampas, nombre)
+		} else {
+			sh.trampas[nombre] = comando
+		}
+		sh.configurarEntregaSenal(nombre)
+	}
+	return nil
+}
+
+// normalizarTrampa convierte una especificación de señal de trap en el nombre
+// usado como clave: "int", "SIGINT" y "2" → "INT"; "exit" y "0" → "EXIT".
+func normalizarTrampa(texto string) (string, error) {
+	nombre := strings.TrimPrefix(strings.ToUpper(texto), "SIG")
+	switch nombre {
+	case trampaSalida, "0":
+		return trampaSalida, nil
+	case trampaError, trampaDepurar, trampaRetorno:
+		return nombre, nil
+	}
+
+	if _, err := strconv.Atoi(texto); err != nil {
+		if _, ok := tablaSenales[nombre]; ok {
+			return nombre, nil
+		}
+		return "", fmt.Errorf("%s: especificación de señal inválida", texto)
+	}
+	senal, err := parsearSenal(texto)
+	if err != nil || senal == 0 {
+		return "", fmt.Errorf("%s: especificación de señal inválida", texto)
+	}
+	return nombreCortoSenal(senal), nil
+}
+
+// configurarEntregaSenal ajusta cómo recibe la shell una señal real tras
+// modificar su trampa. Las pseudo-señales no requieren ninguna configuración.
+//
+// La shell recibe la señal mientras tenga una trampa para ella, incluso
+// vacía: una señal ignorada llega igual a la shell, que la descarta al
+// atenderla. Así ignorar o restaurar una señal solo afecta a esta shell y no
+// al resto del proceso (ver suscribirSenal).
+//
+// Parámetros:
+//   - nombre: nombre normalizado de la señal (nunca KILL ni STOP, que no
+//     pueden atraparse)
+func (sh *Shell) configurarEntregaSenal(nombre string) {
+	senal, ok := tablaSenales[nombre]
+	if !ok {
+		return // Pseudo-señal
+	}
+
+	// SIGHUP tiene un manejo propio de la shell (ver IniciarManejoSenales)
+	_, atrapada := sh.trampas[nombre]
+	sh.suscribirSenal(senal, atrapada || (senal == syscall.SIGHUP && sh.manejoSenales))
+}
+
+// mostrarTrampas implementa "trap -p": muestra cada trampa como el comando
+// trap que la recrea (ej: trap -- 'rm -rf /tmp/x' EXIT).
+//
+// Parámetros:
+//   - filtro: señales a mostrar; vacío significa todas
+//   - w: destino del listado
+func (sh *Shell) mostrarTrampas(filtro []string, w io.Writer) error {
+	nombres := make([]string, 0, len(sh.trampas))
+	if len(filtro) == 0 {
+		for nombre := range sh.trampas {
+			nombres = append(nombres, nombre)
+		}
+		sort.Strings(nombres)
+	} else {
+		for _, texto := range filtro {
+			nombre, err := normalizarTrampa(texto)
+			if err != nil {
+				return fmt.Errorf("trap: %v", err)
+			}
+			nombres = append(nombres, nombre)
+		}
+	}
+
+	for _, nombre := range nombres {
+		comando, ok := sh.trampas[nombre]
+		if !ok {
+			continue
+		}
+		if _, real := tablaSenales[nombre]; real {
+			nombre = "SIG" + nombre
+		}
+		fmt.Fprintf(w, "trap -- '%s' %s\n", comando, nombre)
+	}
+	return nil
+}
+
+// quitarComillas elimina un par de comillas simples o dobles que rodeen el texto
+func quitarComillas(texto string) string {
+	if len(texto) >= 2 {
+		primera, ultima := texto[0], texto[len(texto)-1]
+		if (primera == '\'' || primera == '"') && primera == ultima {
+			return texto[1 : len(texto)-1]
+		}
+	}
+	return texto
+}
+
+// ejecutarTrampa ejecuta el comando asociado a una señal o pseudo-señal, si
+// existe. El estado del último comando ($?) se conserva: el manejador no lo
+// modifica, salvo que llame a exit.
+//
+// Parámetros:
+//   - nombre: nombre normalizado de la señal o pseudo-señal
+//
+// Retorna:
+//   - bool: true si había una trampa definida para la señal
+//   - error: *SalidaShell si el manejador ejecutó exit, nil en otro caso
+func (sh *Shell) ejecutarTrampa(nombre string) (bool, error) {
+	comando, ok := sh.trampas[nombre]
+	if !ok {
+		return false, nil
+	}
+	if comando == "" || sh.enTrampa {
+		return true, nil
+	}
+
+	sh.enTrampa = true
+	defer func() { sh.enTrampa = false }()
+
+	estado := sh.ultimoEstado
+	err := sh.EjecutarLinea(comando)
+
+	var salida *SalidaShell
+	if errors.As(err, &salida) {
+		return true, salida
+	}
+	if err != nil {
+		fmt.Fprintf(sh.Stderr, "trap %s: %v\n", nombre, err)
+	}
+	sh.ultimoEstado = estado
+	return true, nil
+}
+
+// atenderSenal responde a una señal recibida por la shell. Se llama solo desde
+// el bucle REPL (nunca desde la goroutine de señales de Go).
+//
+// Parámetros:
+//   - senal: señal recibida
+//
+// Retorna:
+//   - error: *SalidaShell si la shell debe terminar, nil en otro caso
+func (sh *Shell) atenderSenal(senal syscall.Signal) error {
+	nombre := nombreCortoSenal(senal)
+	definida, err := sh.ejecutarTrampa(nombre)
+	if definida || err != nil {
+		return err
+	}
+
+	// Sin trampa, SIGHUP termina la shell con el estado 128 + 1
+	if senal == syscall.SIGHUP {
+		return &SalidaShell{Estado: 128 + int(senal)}
+	}
+	return nil
+}
+
+// atenderSenalesPendientes atiende, sin bloquear, todas las señales que hayan
+// llegado mientras se ejecutaba un comando.
+//
+// Retorna:
+//   - error: *SalidaShell si la shell debe terminar, nil en otro caso
+func (sh *Shell) atenderSenalesPendientes() error {
+	for {
+		select {
+		case s := <-sh.senales:
+			if senal, ok := s.(syscall.Signal); ok {
+				if err := sh.atenderSenal(senal); err != nil {
+					return err
+				}
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// ejecutarTrampaSalida ejecuta la trampa EXIT. Se registra con sh.alSalir para
+// que se ejecute antes que el resto de la limpieza.
+//
+// Parámetros:
+//   - estado: estado de salida de la shell, visible como $? en el manejador
+//
+// Retorna:
+//   - int: el mismo estado, o el indicado por exit si el manejador lo llama
+func (sh *Shell) ejecutarTrampaSalida(estado int) int {
+	sh.ultimoEstado = estado
+	_, err := sh.ejecutarTrampa(trampaSalida)
+
+	var salida *SalidaShell
+	if errors.As(err, &salida) {
+		return salida.Estado
+	}
+	return estado
+}